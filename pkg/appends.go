@@ -0,0 +1,452 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// appendBounds is the range of elements appended to a slice in a single loop iteration.
+type appendBounds struct {
+	min, max count
+}
+
+// appendPaths maps slices to the elements appended to them along the paths
+// reaching a point in the loop body. A nil map means no path reaches it.
+type appendPaths map[types.Object]appendBounds
+
+func (p appendPaths) clone() appendPaths {
+	if p == nil {
+		return nil
+	}
+	c := make(appendPaths, len(p))
+	for obj, bounds := range p {
+		c[obj] = bounds
+	}
+	return c
+}
+
+// join merges the paths of two alternative branches.
+func (p appendPaths) join(q appendPaths) appendPaths {
+	if p == nil {
+		return q.clone()
+	}
+	if q == nil {
+		return p
+	}
+	for obj, bounds := range q {
+		other := p[obj]
+		p[obj] = appendBounds{min: minCount(bounds.min, other.min), max: maxCount(bounds.max, other.max)}
+	}
+	for obj, bounds := range p {
+		if _, ok := q[obj]; !ok {
+			p[obj] = appendBounds{max: bounds.max}
+		}
+	}
+	return p
+}
+
+// appendCounter walks a loop body, counting the appends made along every
+// path through a single iteration.
+type appendCounter struct {
+	v           *returnsVisitor
+	keys        map[types.Object]bool // variables distinct in every iteration
+	unsupported map[types.Object]bool
+	ended       appendPaths   // paths that leave the iteration early
+	breaks      []breakTarget // enclosing switch and select statements
+}
+
+// breakTarget collects the paths breaking out of a switch or select statement.
+type breakTarget struct {
+	label string
+	paths appendPaths
+}
+
+// countAppends returns the per-iteration append bounds of the slices appended
+// to in the loop body, along with the slices appended to in ways that cannot
+// be preallocated. Map entries are only inserted for sure under keys that are
+// distinct in every iteration.
+func (v *returnsVisitor) countAppends(body *ast.BlockStmt, keys map[types.Object]bool) (*appendCounter, appendPaths) {
+	c := &appendCounter{v: v, keys: keys, unsupported: make(map[types.Object]bool)}
+	paths := c.stmts(body.List, appendPaths{})
+	paths = paths.join(c.ended)
+	for obj := range c.unsupported {
+		delete(paths, obj)
+	}
+	for obj, bounds := range paths {
+		if bounds.max.isZero() {
+			delete(paths, obj)
+		}
+	}
+	return c, paths
+}
+
+func (c *appendCounter) stmts(list []ast.Stmt, paths appendPaths) appendPaths {
+	for _, stmt := range list {
+		if paths == nil {
+			// unreachable
+			break
+		}
+		paths = c.stmt(stmt, paths)
+	}
+	return paths
+}
+
+func (c *appendCounter) stmt(stmt ast.Stmt, paths appendPaths) appendPaths {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		c.assign(s, paths)
+
+	case *ast.IncDecStmt:
+		// e.g., `m[k]++`, counting repeated keys
+		if obj := c.v.mapInsert(s.X); obj != nil {
+			c.unsupported[obj] = true
+		}
+
+	case *ast.BlockStmt:
+		return c.stmts(s.List, paths)
+
+	case *ast.LabeledStmt:
+		switch l := s.Stmt.(type) {
+		case *ast.SwitchStmt:
+			if l.Init != nil {
+				paths = c.stmt(l.Init, paths)
+			}
+			return c.clauses(l.Body, s.Label.Name, paths)
+		case *ast.TypeSwitchStmt:
+			if l.Init != nil {
+				paths = c.stmt(l.Init, paths)
+			}
+			return c.clauses(l.Body, s.Label.Name, paths)
+		case *ast.SelectStmt:
+			return c.clauses(l.Body, s.Label.Name, paths)
+		case *ast.RangeStmt:
+			return c.rangeLoop(s, l, paths)
+		case *ast.ForStmt:
+			return c.forLoop(s, l, paths)
+		}
+		return c.stmt(s.Stmt, paths)
+
+	case *ast.IfStmt:
+		if s.Init != nil {
+			paths = c.stmt(s.Init, paths)
+		}
+		then := c.stmts(s.Body.List, paths.clone())
+		if s.Else != nil {
+			return then.join(c.stmt(s.Else, paths))
+		}
+		return then.join(paths)
+
+	case *ast.SwitchStmt:
+		if s.Init != nil {
+			paths = c.stmt(s.Init, paths)
+		}
+		return c.clauses(s.Body, "", paths)
+
+	case *ast.TypeSwitchStmt:
+		if s.Init != nil {
+			paths = c.stmt(s.Init, paths)
+		}
+		return c.clauses(s.Body, "", paths)
+
+	case *ast.SelectStmt:
+		return c.clauses(s.Body, "", paths)
+
+	case *ast.BranchStmt:
+		if target := c.breakTarget(s); target != nil {
+			// break out of an enclosing switch or select
+			target.paths = target.paths.join(paths)
+		} else {
+			// continue, or an exit from the loop
+			c.ended = c.ended.join(paths)
+		}
+		return nil
+
+	case *ast.ReturnStmt:
+		c.ended = c.ended.join(paths)
+		return nil
+
+	case *ast.ExprStmt:
+		c.write(s.X, paths)
+		if call, ok := s.X.(*ast.CallExpr); ok && !c.v.mayReturn(call) {
+			c.ended = c.ended.join(paths)
+			return nil
+		}
+
+	case *ast.RangeStmt:
+		return c.rangeLoop(s, s, paths)
+
+	case *ast.ForStmt:
+		return c.forLoop(s, s, paths)
+	}
+
+	return paths
+}
+
+// breakTarget returns the switch or select statement a branch breaks out of,
+// or nil if it targets a loop.
+func (c *appendCounter) breakTarget(branch *ast.BranchStmt) *breakTarget {
+	if branch.Tok != token.BREAK {
+		return nil
+	}
+	for i := len(c.breaks) - 1; i >= 0; i-- {
+		if branch.Label == nil || c.breaks[i].label == branch.Label.Name {
+			return &c.breaks[i]
+		}
+	}
+	return nil
+}
+
+func (c *appendCounter) rangeLoop(loopStmt ast.Stmt, loop *ast.RangeStmt, paths appendPaths) appendPaths {
+	if !c.v.includeRangeLoops {
+		c.unsupportedLoop(loop)
+		return paths
+	}
+	return c.nestedLoop(loopStmt, loop.Body, c.v.rangeLoopCount(loop), true, paths)
+}
+
+func (c *appendCounter) forLoop(loopStmt ast.Stmt, loop *ast.ForStmt, paths appendPaths) appendPaths {
+	if !c.v.includeForLoops {
+		c.unsupportedLoop(loop)
+		return paths
+	}
+	iterations, exact := c.v.forLoopCount(loop)
+	return c.nestedLoop(loopStmt, loop.Body, iterations, exact, paths)
+}
+
+// nestedLoop adds the appends made by every iteration of a nested loop, whose
+// number of iterations may only be an upper bound.
+func (c *appendCounter) nestedLoop(loop ast.Stmt, body *ast.BlockStmt, iterations ast.Expr, exact bool, paths appendPaths) appendPaths {
+	if n, ok := exprIntValue(iterations); ok && n <= 0 {
+		// loop will definitely never iterate
+		return paths
+	}
+
+	// keys repeat between the iterations of the enclosing loop
+	inner, innerPaths := c.v.countAppends(body, nil)
+	for obj := range inner.unsupported {
+		c.unsupported[obj] = true
+	}
+
+	exits, escapes := c.v.loopExits(loop)
+	mutated := c.v.loopCountMutated(loop) != ""
+	for obj, bounds := range innerPaths {
+		if iterations == nil || iterations == invalid || mutated {
+			// appends within a loop of unknown length cannot be counted
+			c.unsupported[obj] = true
+			continue
+		}
+		total := paths[obj]
+		total.max = total.max.add(c.v.loopTotal(bounds.max, loop, iterations))
+		if !exits && exact {
+			total.min = total.min.add(c.v.loopTotal(bounds.min, loop, iterations))
+		}
+		paths[obj] = total
+	}
+	if escapes {
+		// the rest of the iteration may be skipped
+		c.ended = c.ended.join(paths)
+	}
+	return paths
+}
+
+// unsupportedLoop marks every slice appended to within the loop as unsupported.
+func (c *appendCounter) unsupportedLoop(loop ast.Stmt) {
+	ast.Inspect(loop, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			for obj := range c.appendTargets(n) {
+				c.unsupported[obj] = true
+			}
+		case *ast.IncDecStmt:
+			if obj := c.v.mapInsert(n.X); obj != nil {
+				c.unsupported[obj] = true
+			}
+		case *ast.CallExpr:
+			if obj, _, _ := c.v.builderWrite(n); obj != nil {
+				c.unsupported[obj] = true
+			}
+		}
+		return true
+	})
+}
+
+// clauses joins the paths through the case or comm clauses of a switch or select body.
+func (c *appendCounter) clauses(body *ast.BlockStmt, label string, paths appendPaths) appendPaths {
+	c.breaks = append(c.breaks, breakTarget{label: label})
+
+	var out, fallthroughPaths appendPaths
+	hasDefault := false
+	for _, clause := range body.List {
+		var list []ast.Stmt
+		switch cc := clause.(type) {
+		case *ast.CaseClause:
+			hasDefault = hasDefault || cc.List == nil
+			list = cc.Body
+		case *ast.CommClause:
+			// a select without a default blocks until one of its cases runs
+			hasDefault = true
+			list = cc.Body
+			if cc.Comm != nil {
+				list = append([]ast.Stmt{cc.Comm}, list...)
+			}
+		}
+
+		clausePaths := paths.clone().join(fallthroughPaths)
+		fallthroughPaths = nil
+		if n := len(list); n > 0 {
+			if branch, ok := list[n-1].(*ast.BranchStmt); ok && branch.Tok == token.FALLTHROUGH {
+				fallthroughPaths = c.stmts(list[:n-1], clausePaths)
+				continue
+			}
+		}
+		out = out.join(c.stmts(list, clausePaths))
+	}
+	if !hasDefault {
+		out = out.join(paths)
+	}
+
+	out = out.join(c.breaks[len(c.breaks)-1].paths)
+	c.breaks = c.breaks[:len(c.breaks)-1]
+	return out
+}
+
+// assign records the appends made by an assignment along the current paths.
+func (c *appendCounter) assign(stmt *ast.AssignStmt, paths appendPaths) {
+	if len(stmt.Rhs) == 1 {
+		c.write(stmt.Rhs[0], paths)
+	}
+	repeated := c.repeatedInserts(stmt)
+	for obj, n := range c.appendTargets(stmt) {
+		if n.isZero() {
+			c.unsupported[obj] = true
+			continue
+		}
+		bounds := paths[obj]
+		paths[obj] = appendBounds{min: bounds.min.add(n.add(constCount(-repeated[obj]))), max: bounds.max.add(n)}
+	}
+}
+
+// repeatedInserts returns the number of entries an assignment inserts into
+// each map under keys that may repeat between iterations, e.g. `m[v] = true`
+// for the elements v of a slice, which may already be present.
+func (c *appendCounter) repeatedInserts(stmt *ast.AssignStmt) map[types.Object]int {
+	repeated := make(map[types.Object]int)
+	for _, lhs := range stmt.Lhs {
+		obj := c.v.mapInsert(lhs)
+		if obj == nil {
+			continue
+		}
+		key, ok := ast.Unparen(ast.Unparen(lhs).(*ast.IndexExpr).Index).(*ast.Ident)
+		if !ok || !c.keys[c.v.pass.TypesInfo.Uses[key]] {
+			repeated[obj]++
+		}
+	}
+	return repeated
+}
+
+// write records the bytes written to a builder by a call along the current paths.
+func (c *appendCounter) write(expr ast.Expr, paths appendPaths) {
+	obj, least, most := c.v.builderWrite(expr)
+	if obj == nil {
+		return
+	}
+	if most.isZero() {
+		c.unsupported[obj] = true
+		return
+	}
+	bounds := paths[obj]
+	paths[obj] = appendBounds{min: bounds.min.add(least), max: bounds.max.add(most)}
+}
+
+// add records n elements appended to the slice along every path.
+func (p appendPaths) add(obj types.Object, n count) {
+	bounds := p[obj]
+	p[obj] = appendBounds{min: bounds.min.add(n), max: bounds.max.add(n)}
+}
+
+// appendTargets returns the number of elements appended to each slice, or
+// inserted into each map, by an assignment, with zero marking an unsupported
+// append pattern.
+func (c *appendCounter) appendTargets(stmt *ast.AssignStmt) map[types.Object]count {
+	var targets map[types.Object]count
+	for i, lhs := range stmt.Lhs {
+		if obj := c.v.mapInsert(lhs); obj != nil {
+			// e.g., `m[k] = v`, but not `m[k] += n`, counting repeated keys
+			if targets == nil {
+				targets = make(map[types.Object]count)
+			}
+			if stmt.Tok == token.ASSIGN || stmt.Tok == token.DEFINE {
+				targets[obj] = targets[obj].add(constCount(1))
+			} else {
+				targets[obj] = count{}
+			}
+			continue
+		}
+		if i >= len(stmt.Rhs) {
+			break
+		}
+
+		// e.g., `x`, `res.Rows` or `*out`
+		lhsObj := c.v.pathObj(lhs)
+		if lhsObj == nil {
+			continue
+		}
+
+		callExpr, ok := stmt.Rhs[i].(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+
+		if !c.v.isBuiltin(callExpr.Fun, "append") {
+			continue
+		}
+
+		// e.g., `x = append(x)`
+		// Pointless, but pre-allocation will not help.
+		if len(callExpr.Args) < 2 {
+			continue
+		}
+
+		rhsObj := c.v.pathObj(callExpr.Args[0])
+		if rhsObj == nil {
+			continue
+		}
+
+		if targets == nil {
+			targets = make(map[types.Object]count)
+		} else if n, ok := targets[lhsObj]; ok && n.isZero() {
+			// already ineligible due to unsupported append pattern
+			continue
+		}
+
+		// e.g., `x = append(y, a)`
+		// This is weird (and maybe a logic error),
+		// but we cannot recommend pre-allocation.
+		if lhsObj != rhsObj {
+			targets[lhsObj] = count{}
+			continue
+		}
+
+		if callExpr.Ellipsis.IsValid() {
+			// e.g., `x = append(x, y...)`
+			// which can only be counted if the length of y is known.
+			n := c.v.spreadLen(callExpr.Args[1])
+			if n == nil {
+				targets[lhsObj] = count{}
+				continue
+			}
+			if size, ok := exprIntValue(n); !ok {
+				targets[lhsObj] = targets[lhsObj].add(constCount(1).mul(n))
+			} else if size > 0 {
+				targets[lhsObj] = targets[lhsObj].add(constCount(size))
+			}
+			continue
+		}
+
+		targets[lhsObj] = targets[lhsObj].add(constCount(len(callExpr.Args) - 1))
+	}
+	return targets
+}
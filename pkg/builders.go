@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"unicode/utf8"
+)
+
+// utf8Pkg refers to the unicode/utf8 package, which the fix imports if needed.
+var utf8Pkg = ast.NewIdent("utf8")
+
+// utfMax builds a reference to utf8.UTFMax, the most bytes a rune can encode to.
+func utfMax() ast.Expr {
+	return &ast.SelectorExpr{X: utf8Pkg, Sel: ast.NewIdent("UTFMax")}
+}
+
+// utfMaxValue is the literal checked in place of utf8.UTFMax.
+var utfMaxValue = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(utf8.UTFMax)}
+
+// isBuilderType reports whether t is a strings.Builder or bytes.Buffer.
+func isBuilderType(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	switch named.Obj().Pkg().Path() + "." + named.Obj().Name() {
+	case "strings.Builder", "bytes.Buffer":
+		return true
+	}
+	return false
+}
+
+func (v *returnsVisitor) isBuilder(expr ast.Expr) bool {
+	return isBuilderType(v.pass.TypesInfo.TypeOf(expr))
+}
+
+// isCreateBuilder reports whether expr is an empty builder, e.g. `strings.Builder{}`.
+func (v *returnsVisitor) isCreateBuilder(expr ast.Expr) bool {
+	lit, ok := expr.(*ast.CompositeLit)
+	return ok && lit.Type != nil && len(lit.Elts) == 0 && v.isBuilder(lit)
+}
+
+// builderWrite returns the builder variable written to by a call such as
+// `sb.WriteString(s)`, along with the least and most bytes written. The
+// counts are zero if the number of bytes is unknown.
+func (v *returnsVisitor) builderWrite(expr ast.Expr) (types.Object, count, count) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil, count{}, count{}
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !v.isBuilder(sel.X) {
+		return nil, count{}, count{}
+	}
+	ident, ok := ast.Unparen(sel.X).(*ast.Ident)
+	if !ok {
+		return nil, count{}, count{}
+	}
+	obj := v.pass.TypesInfo.Uses[ident]
+	if obj == nil || len(call.Args) != 1 {
+		return nil, count{}, count{}
+	}
+
+	switch sel.Sel.Name {
+	case "WriteString", "Write":
+		n := v.spreadLen(call.Args[0])
+		if n == nil {
+			return obj, count{}, count{}
+		}
+		if size, ok := exprIntValue(n); ok {
+			return obj, constCount(size), constCount(size)
+		}
+		return obj, constCount(1).mul(n), constCount(1).mul(n)
+	case "WriteByte":
+		return obj, constCount(1), constCount(1)
+	case "WriteRune":
+		if tv := v.pass.TypesInfo.Types[call.Args[0]]; tv.Value != nil && tv.Value.Kind() == constant.Int {
+			if r, ok := constant.Int64Val(tv.Value); ok {
+				if size := utf8.RuneLen(rune(r)); size > 0 {
+					return obj, constCount(size), constCount(size)
+				}
+			}
+		}
+		return obj, constCount(1), constCount(1).mul(utfMax())
+	}
+	return nil, count{}, count{}
+}
+
+// isBuilderMethod reports whether a call to the method of a builder only
+// writes to it or reads it, such that growing it beforehand is safe.
+func isBuilderMethod(name string) bool {
+	switch name {
+	case "WriteString", "Write", "WriteByte", "WriteRune", "Len", "Cap", "String", "Bytes":
+		return true
+	}
+	return false
+}
+
+// foldWrite adds the bytes written to an in-scope builder outside of any loop,
+// between loops writing to it, to the size it is grown by.
+func (v *returnsVisitor) foldWrite(expr ast.Expr) {
+	obj, least, most := v.builderWrite(expr)
+	if obj == nil {
+		return
+	}
+	sliceDecl := v.findSlice(obj)
+	if sliceDecl == nil || sliceDecl.kind != builderKind || sliceDecl.touched != "" {
+		return
+	}
+	if sliceDecl.loop == nil {
+		// written before the builder is grown
+		return
+	}
+	if most.isZero() {
+		sliceDecl.touched = "wrote an unknown number of bytes"
+		return
+	}
+	sliceDecl.pending = exprIntAdd(sliceDecl.pending, most.expr())
+	if v.depth > sliceDecl.depth || !least.equal(most) {
+		// the write may not happen, or may be shorter
+		sliceDecl.pendingUpperBound = true
+	}
+}
@@ -0,0 +1,430 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// capacityAt returns the capacity expression, converted to int where needed,
+// if it compiles at pos. Capacities are computed from loop headers, which may
+// refer to variables declared after the slice, and are evaluated again before
+// the loop, so they must not have side effects.
+func (v *returnsVisitor) capacityAt(expr ast.Expr, pos token.Pos) (ast.Expr, bool) {
+	if !v.sideEffectFree(expr) {
+		return nil, false
+	}
+	if tv, ok := v.checkExpr(expr, pos); ok {
+		return expr, isInteger(tv.Type)
+	}
+	expr, ok := v.intExpr(expr, pos)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := v.checkExpr(expr, pos); !ok {
+		return nil, false
+	}
+	return expr, true
+}
+
+func isInteger(t types.Type) bool {
+	basic, ok := coreType(t).(*types.Basic)
+	return ok && basic.Info()&types.IsInteger != 0
+}
+
+// intExpr converts the terms of a sum or product that are not of type int,
+// such as typed loop bounds, so that they can be combined.
+func (v *returnsVisitor) intExpr(expr ast.Expr, pos token.Pos) (ast.Expr, bool) {
+	if tv, ok := v.checkExpr(expr, pos); ok {
+		switch {
+		case !isInteger(tv.Type):
+			return nil, false
+		case types.Identical(tv.Type, types.Typ[types.Int]) || types.Identical(tv.Type, types.Typ[types.UntypedInt]):
+			return expr, true
+		}
+		return &ast.CallExpr{Fun: ast.NewIdent("int"), Args: []ast.Expr{expr}}, true
+	}
+
+	bin, ok := expr.(*ast.BinaryExpr)
+	if !ok || (bin.Op != token.ADD && bin.Op != token.SUB && bin.Op != token.MUL) {
+		return nil, false
+	}
+	x, ok := v.intExpr(bin.X, pos)
+	if !ok {
+		return nil, false
+	}
+	y, ok := v.intExpr(bin.Y, pos)
+	if !ok {
+		return nil, false
+	}
+	return &ast.BinaryExpr{X: x, Op: bin.Op, Y: y}, true
+}
+
+// checkExpr type checks expr as if it appeared at pos, where each identifier
+// must refer to the same object as where it appears in the source, rather
+// than to another declared in between or shadowing it.
+func (v *returnsVisitor) checkExpr(expr ast.Expr, pos token.Pos) (types.TypeAndValue, bool) {
+	expr = withoutImports(expr)
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	if types.CheckExpr(v.pass.Fset, v.pass.Pkg, pos, expr, info) != nil {
+		return types.TypeAndValue{}, false
+	}
+	for ident, obj := range info.Uses {
+		if orig := v.pass.TypesInfo.Uses[ident]; orig != nil && orig != obj {
+			return types.TypeAndValue{}, false
+		}
+	}
+	return info.Types[expr], true
+}
+
+// sideEffectFree reports whether evaluating expr again has no side effects,
+// unlike `len(<-ch)` or `len(s.get())`. Calls built from loop headers are
+// builtins and conversions.
+func (v *returnsVisitor) sideEffectFree(expr ast.Expr) bool {
+	free := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.UnaryExpr:
+			free = n.Op != token.ARROW
+		case *ast.CallExpr:
+			tv, ok := v.pass.TypesInfo.Types[n.Fun]
+			free = !ok || tv.IsType() || tv.IsBuiltin()
+		}
+		return free
+	})
+	return free
+}
+
+// changedAfter reports whether a variable the capacity refers to may be
+// written after pos and before the last loop appending to the slice, such
+// that the capacity evaluated at pos would be stale, e.g. `n = n * 2` or
+// `a = append(a, v)` between the declaration and the loop.
+func (v *returnsVisitor) changedAfter(sliceDecl *sliceDeclaration, pos token.Pos) bool {
+	objs := v.referencedVars(sliceDecl.capExpr)
+	if len(objs) == 0 {
+		return false
+	}
+	for _, stmt := range sliceDecl.list {
+		if stmt == sliceDecl.stmt || stmt.End() <= pos || stmt.Pos() >= sliceDecl.last.Pos() {
+			continue
+		}
+		// the last loop's own writes are checked against its count
+		if v.written(stmt, objs, sliceDecl.last) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// bitsPkg refers to the math/bits package, which the fix imports if needed.
+var bitsPkg = ast.NewIdent("bits")
+
+// bitsLen builds a call to bits.Len, the number of bits needed to represent x.
+func bitsLen(x ast.Expr) ast.Expr {
+	return &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: bitsPkg, Sel: ast.NewIdent("Len")},
+		Args: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent("uint"), Args: []ast.Expr{x}}},
+	}
+}
+
+// usesPkg reports whether expr refers to the given package, such as bitsPkg.
+func usesPkg(expr ast.Expr, pkg *ast.Ident) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		found = found || n == pkg
+		return !found
+	})
+	return found
+}
+
+// withoutImports replaces calls to bits.Len with int conversions of their
+// argument, and utf8.UTFMax with its value, such that the expression can be
+// checked where math/bits or unicode/utf8 is not yet imported.
+func withoutImports(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		return &ast.BinaryExpr{X: withoutImports(e.X), Op: e.Op, Y: withoutImports(e.Y)}
+	case *ast.ParenExpr:
+		return &ast.ParenExpr{X: withoutImports(e.X)}
+	case *ast.UnaryExpr:
+		return &ast.UnaryExpr{Op: e.Op, X: withoutImports(e.X)}
+	case *ast.SelectorExpr:
+		if e.X == utf8Pkg {
+			return utfMaxValue
+		}
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.X == bitsPkg {
+			return &ast.CallExpr{Fun: ast.NewIdent("int"), Args: []ast.Expr{withoutImports(e.Args[0])}}
+		}
+		args := make([]ast.Expr, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = withoutImports(arg)
+		}
+		return &ast.CallExpr{Fun: e.Fun, Args: args, Ellipsis: e.Ellipsis}
+	}
+	return expr
+}
+
+// canMove reports whether the declaration of the slice can be moved down to
+// the first loop appending to it, being a lone declaration of an empty slice
+// in the same statement list as the loop with no references in between.
+func (v *returnsVisitor) canMove(sliceDecl *sliceDeclaration) bool {
+	if !sliceDecl.fixable || sliceDecl.loop == nil {
+		return false
+	}
+	switch s := sliceDecl.stmt.(type) {
+	case *ast.AssignStmt:
+		if s.Tok != token.DEFINE || len(s.Lhs) != 1 {
+			return false
+		}
+	case *ast.DeclStmt:
+		genD := s.Decl.(*ast.GenDecl)
+		if genD.Lparen.IsValid() || len(genD.Specs) != 1 || len(sliceDecl.spec.Names) != 1 {
+			return false
+		}
+	default:
+		return false
+	}
+
+	declIndex, loopIndex := -1, -1
+	for i, stmt := range sliceDecl.list {
+		switch stmt {
+		case sliceDecl.stmt:
+			declIndex = i
+		case sliceDecl.loop:
+			loopIndex = i
+		}
+	}
+	if declIndex < 0 || loopIndex <= declIndex {
+		return false
+	}
+
+	for _, stmt := range sliceDecl.list[declIndex+1 : loopIndex] {
+		referenced := false
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && v.pass.TypesInfo.Uses[ident] == sliceDecl.obj {
+				referenced = true
+			}
+			return !referenced
+		})
+		if referenced {
+			return false
+		}
+	}
+	return true
+}
+
+// assignedBefore reports whether the slice is assigned to between its
+// declaration and the first loop appending to it, such that making it just
+// before the loop would discard what was assigned.
+func (v *returnsVisitor) assignedBefore(sliceDecl *sliceDeclaration) bool {
+	assigned := false
+	for _, stmt := range sliceDecl.list {
+		if stmt == sliceDecl.stmt || stmt.End() <= sliceDecl.pos || stmt.Pos() >= sliceDecl.loop.Pos() {
+			continue
+		}
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if n == sliceDecl.loop {
+				return false
+			}
+			if s, ok := n.(*ast.AssignStmt); ok {
+				for _, lhs := range s.Lhs {
+					assigned = assigned || v.writtenObj(lhs) == sliceDecl.obj
+				}
+			}
+			return !assigned
+		})
+		if assigned {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCapacity reports a slice made with an explicit capacity that is stale,
+// referring to the length of a different collection than the loops appending
+// to it, or provably smaller than the computed capacity.
+func (v *returnsVisitor) checkCapacity(sliceDecl *sliceDeclaration) {
+	if sliceDecl.perIteration != nil || sliceDecl.capExpr == nil || sliceDecl.capExpr == invalid || sliceDecl.move ||
+		sliceDecl.made == nil {
+		return
+	}
+	capText, ok := exprText(sliceDecl.capArg)
+	if !ok {
+		return
+	}
+	// combine like terms, e.g., `len(a) + 1` rather than `2 + (len(a) - 1)`
+	need, ok := linearCount(sliceDecl.capExpr)
+	if !ok || need.expr() == nil {
+		return
+	}
+	needText, ok := exprText(need.expr())
+	if !ok {
+		return
+	}
+
+	var problem string
+	switch {
+	case sliceDecl.upperBound:
+		// a loop filtering elements may be sized by another collection on
+		// purpose, e.g., the elements marked to keep
+		return
+	case v.staleCapacity(sliceDecl.capArg, sliceDecl.capExpr):
+		problem = "looks stale"
+	case tooSmall(sliceDecl.capArg, sliceDecl.capExpr):
+		problem = "is too small"
+	default:
+		return
+	}
+
+	diag := analysis.Diagnostic{
+		Pos:      sliceDecl.pos,
+		Category: CategorySlice,
+		Message:  "Capacity of " + sliceDecl.name + " " + problem + ": made with " + capText + ", but needs " + needText,
+	}
+	// the make call is formatted as a whole, e.g., `make([]T, 0, 2*len(a))`
+	made := *sliceDecl.made
+	made.Args = []ast.Expr{made.Args[0], made.Args[1], need.expr()}
+	madeText, ok := exprText(&made)
+	if edits, editsOK := v.importEdits(sliceDecl.pos, sliceDecl.capExpr); ok && editsOK {
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: "Make " + sliceDecl.name + " with capacity " + needText,
+			TextEdits: append([]analysis.TextEdit{
+				{Pos: made.Pos(), End: made.End(), NewText: []byte(madeText)},
+			}, edits...),
+		}}
+	}
+	v.preallocHints = append(v.preallocHints, diag)
+}
+
+// staleCapacity reports whether the capacity refers only to the lengths of
+// collections that the needed capacity does not.
+func (v *returnsVisitor) staleCapacity(capArg, need ast.Expr) bool {
+	have, needed := v.lengthsOf(capArg), v.lengthsOf(need)
+	if len(have) == 0 || len(needed) == 0 {
+		return false
+	}
+	for text := range have {
+		if needed[text] {
+			return false
+		}
+	}
+	return true
+}
+
+// lengthsOf returns the collections whose length or capacity expr refers to,
+// identified by what they are sliced or indexed from, such that `len(a[1:])`
+// refers to the same collection as `len(a)`.
+func (v *returnsVisitor) lengthsOf(expr ast.Expr) map[string]bool {
+	lengths := make(map[string]bool)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 || !v.isLenOrCap(call.Fun) {
+			return true
+		}
+		if text, ok := exprText(collectionRoot(call.Args[0])); ok {
+			lengths[text] = true
+		}
+		return false
+	})
+	return lengths
+}
+
+// collectionRoot strips the slice and index expressions from expr, e.g. `a`
+// for `a[1:]` or `(*p)[i][:n]`.
+func collectionRoot(expr ast.Expr) ast.Expr {
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.SliceExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		default:
+			return expr
+		}
+	}
+}
+
+// isLenOrCap reports whether expr refers to the len or cap builtin, including
+// in calls built from loop headers, which are not type checked.
+func (v *returnsVisitor) isLenOrCap(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || (ident.Name != "len" && ident.Name != "cap") {
+		return false
+	}
+	obj := v.pass.TypesInfo.Uses[ident]
+	_, isBuiltin := obj.(*types.Builtin)
+	return obj == nil || isBuiltin
+}
+
+// tooSmall reports whether the capacity is less than the needed capacity,
+// term by term, assuming every term is non-negative.
+func tooSmall(capArg, need ast.Expr) bool {
+	have, ok := linearCount(capArg)
+	if !ok {
+		return false
+	}
+	needed, ok := linearCount(need)
+	if !ok {
+		return false
+	}
+	for _, t := range have.terms {
+		if needed.coef(t.key) < t.coef {
+			return false
+		}
+	}
+	less := false
+	for _, t := range needed.terms {
+		switch c := have.coef(t.key); {
+		case c > t.coef:
+			return false
+		case c < t.coef:
+			less = true
+		}
+	}
+	return less
+}
+
+// linearCount parses a sum of constant multiples of terms, such that it can be
+// compared with another term by term.
+func linearCount(expr ast.Expr) (count, bool) {
+	if n, ok := exprIntValue(expr); ok {
+		return constCount(n), true
+	}
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return linearCount(e.X)
+	case *ast.BinaryExpr:
+		x, ok := linearCount(e.X)
+		if !ok {
+			return count{}, false
+		}
+		y, ok := linearCount(e.Y)
+		if !ok {
+			return count{}, false
+		}
+		switch e.Op {
+		case token.ADD:
+			return x.add(y), true
+		case token.SUB:
+			return x.add(y.mul(&ast.BasicLit{Kind: token.INT, Value: "-1"})), true
+		case token.MUL:
+			if _, ok := exprIntValue(e.X); ok {
+				return y.mul(e.X), true
+			}
+			if _, ok := exprIntValue(e.Y); ok {
+				return x.mul(e.Y), true
+			}
+		}
+	}
+	return constCount(1).mul(expr), true
+}
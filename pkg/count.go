@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// term is a constant multiple of the product of its factors.
+type term struct {
+	coef    int
+	factors []ast.Expr
+	key     string // formatted factors, identifying like terms
+}
+
+// count is a non-negative number of elements expressed as a sum of terms.
+type count struct {
+	terms []term
+	// perIteration is set when the count is a sum over the iterations of an
+	// enclosing loop whose individual iterations append varying numbers of
+	// elements, such that only a counting pre-pass can determine it.
+	perIteration ast.Expr
+}
+
+func constCount(n int) count {
+	if n == 0 {
+		return count{}
+	}
+	return count{terms: []term{{coef: n}}}
+}
+
+func (c count) isZero() bool {
+	return len(c.terms) == 0 && c.perIteration == nil
+}
+
+func (c count) coef(key string) int {
+	for _, t := range c.terms {
+		if t.key == key {
+			return t.coef
+		}
+	}
+	return 0
+}
+
+// combine merges the like terms of c and d using op, preserving term order.
+func (c count) combine(d count, op func(x, y int) int) count {
+	var out count
+	seen := make(map[string]bool, len(c.terms)+len(d.terms))
+	for _, terms := range [][]term{c.terms, d.terms} {
+		for _, t := range terms {
+			if seen[t.key] {
+				continue
+			}
+			seen[t.key] = true
+			if coef := op(c.coef(t.key), d.coef(t.key)); coef != 0 {
+				t.coef = coef
+				out.terms = append(out.terms, t)
+			}
+		}
+	}
+	out.perIteration = c.perIteration
+	if out.perIteration == nil {
+		out.perIteration = d.perIteration
+	}
+	return out
+}
+
+func (c count) add(d count) count {
+	return c.combine(d, func(x, y int) int { return x + y })
+}
+
+func minCount(c, d count) count {
+	return c.combine(d, func(x, y int) int { return min(x, y) })
+}
+
+func maxCount(c, d count) count {
+	return c.combine(d, func(x, y int) int { return max(x, y) })
+}
+
+func (c count) equal(d count) bool {
+	if len(c.terms) != len(d.terms) || (c.perIteration == nil) != (d.perIteration == nil) {
+		return false
+	}
+	for _, t := range c.terms {
+		if d.coef(t.key) != t.coef {
+			return false
+		}
+	}
+	return true
+}
+
+// mul multiplies every term by the given factor.
+func (c count) mul(factor ast.Expr) count {
+	if n, ok := exprIntValue(factor); ok {
+		out := count{perIteration: c.perIteration}
+		if n == 0 {
+			return out
+		}
+		for _, t := range c.terms {
+			t.coef *= n
+			out.terms = append(out.terms, t)
+		}
+		return out
+	}
+
+	text, ok := exprText(factor)
+	if !ok {
+		text = "?"
+	}
+	out := count{perIteration: c.perIteration}
+	for _, t := range c.terms {
+		t.factors = append([]ast.Expr{factor}, t.factors...)
+		if t.key == "" {
+			t.key = text
+		} else {
+			t.key = text + " * " + t.key
+		}
+		out.terms = append(out.terms, t)
+	}
+	return out
+}
+
+// expr builds the expression for the count, or nil if it is zero.
+func (c count) expr() ast.Expr {
+	var sum ast.Expr
+	for _, t := range c.terms {
+		var product ast.Expr
+		for _, factor := range t.factors {
+			if product == nil {
+				product = factor
+			} else {
+				product = &ast.BinaryExpr{X: product, Op: token.MUL, Y: factor}
+			}
+		}
+		switch {
+		case product == nil:
+			product = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(t.coef)}
+		case t.coef != 1:
+			product = &ast.BinaryExpr{
+				X:  &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(t.coef)},
+				Op: token.MUL,
+				Y:  product,
+			}
+		}
+		sum = exprIntAdd(sum, product)
+	}
+	return sum
+}
+
+// loopTotal multiplies the elements appended per iteration by the number of
+// iterations of the loop. When the per-iteration count depends on variables
+// declared or assigned by the loop, the total varies with the elements
+// iterated over.
+func (v *returnsVisitor) loopTotal(perIteration count, loop ast.Stmt, iterations ast.Expr) count {
+	if perIteration.perIteration != nil {
+		return perIteration
+	}
+	for _, t := range perIteration.terms {
+		for _, factor := range t.factors {
+			if v.declaredWithin(factor, loop) || v.written(unlabel(loop), v.referencedVars(factor), nil) != nil {
+				return count{perIteration: perIteration.expr()}
+			}
+		}
+	}
+	return perIteration.mul(iterations)
+}
+
+// declaredWithin reports whether expr refers to any object declared within node.
+func (v *returnsVisitor) declaredWithin(expr ast.Expr, node ast.Node) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || found {
+			return !found
+		}
+		if obj := v.pass.TypesInfo.Uses[ident]; obj != nil && obj.Pos() >= node.Pos() && obj.Pos() < node.End() {
+			if _, isVar := obj.(*types.Var); isVar {
+				found = true
+			}
+		}
+		return true
+	})
+	return found
+}
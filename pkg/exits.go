@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/cfg"
+)
+
+// noReturnFuncs are functions that never return to their caller.
+var noReturnFuncs = map[string]bool{
+	"os.Exit":                   true,
+	"runtime.Goexit":            true,
+	"log.Fatal":                 true,
+	"log.Fatalf":                true,
+	"log.Fatalln":               true,
+	"log.Panic":                 true,
+	"log.Panicf":                true,
+	"log.Panicln":               true,
+	"(*log.Logger).Fatal":       true,
+	"(*log.Logger).Fatalf":      true,
+	"(*log.Logger).Fatalln":     true,
+	"(*log.Logger).Panic":       true,
+	"(*log.Logger).Panicf":      true,
+	"(*log.Logger).Panicln":     true,
+	"(*testing.common).Fatal":   true,
+	"(*testing.common).Fatalf":  true,
+	"(*testing.common).FailNow": true,
+	"(*testing.common).Skip":    true,
+	"(*testing.common).Skipf":   true,
+	"(*testing.common).SkipNow": true,
+}
+
+// mayReturn reports whether the call may return to its caller.
+func (v *returnsVisitor) mayReturn(call *ast.CallExpr) bool {
+	if v.isBuiltin(call.Fun, "panic") {
+		return false
+	}
+
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return true
+	}
+	fn, ok := v.pass.TypesInfo.Uses[ident].(*types.Func)
+	return !ok || !noReturnFuncs[fn.FullName()]
+}
+
+// loopExits reports whether any path through the body of the loop leaves the
+// loop other than by starting the next iteration, e.g. by break, return, goto,
+// a labeled branch to an outer statement or a call that never returns. It also
+// reports whether any path escapes to somewhere other than the statement
+// following the loop.
+func (v *returnsVisitor) loopExits(loopStmt ast.Stmt) (exits, escapes bool) {
+	// build the graph from the labeled statement so that branches
+	// targeting the label resolve to the loop
+	graph := cfg.New(&ast.BlockStmt{List: []ast.Stmt{loopStmt}}, v.mayReturn)
+
+	loop := unlabel(loopStmt)
+	var body, done *cfg.Block
+	next := make(map[*cfg.Block]bool)
+	for _, block := range graph.Blocks {
+		if block.Stmt != loop {
+			continue
+		}
+		switch block.Kind {
+		case cfg.KindRangeBody, cfg.KindForBody:
+			body = block
+		case cfg.KindRangeDone, cfg.KindForDone:
+			done = block
+		case cfg.KindRangeLoop, cfg.KindForLoop, cfg.KindForPost:
+			// targets of continue
+			next[block] = true
+		}
+	}
+	if body == nil {
+		return false, false
+	}
+
+	seen := map[*cfg.Block]bool{body: true}
+	queue := []*cfg.Block{body}
+	for len(queue) > 0 {
+		block := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if len(block.Succs) == 0 && block.Kind != cfg.KindSelectAfterCase {
+			// return, a call that never returns or a branch to an enclosing
+			// statement (a select without a default ends in an empty case
+			// block that can never actually be reached)
+			return true, true
+		}
+		for _, succ := range block.Succs {
+			if succ == done {
+				exits = true
+				continue
+			}
+			if next[succ] || seen[succ] {
+				continue
+			}
+			seen[succ] = true
+			queue = append(queue, succ)
+		}
+	}
+	return exits, false
+}
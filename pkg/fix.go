@@ -0,0 +1,326 @@
+package pkg
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// suggestMake builds a fix that rewrites the declaration of the slice into a
+// make call with the computed capacity.
+func (v *returnsVisitor) suggestMake(sliceDecl *sliceDeclaration) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Preallocate " + sliceDecl.name}
+
+	switch s := sliceDecl.stmt.(type) {
+	case *ast.AssignStmt:
+		// x := []T{}
+		text, ok := makeText(sliceDecl)
+		if !ok {
+			return fix, false
+		}
+		value := s.Rhs[sliceDecl.index]
+		fix.TextEdits = []analysis.TextEdit{{Pos: value.Pos(), End: value.End(), NewText: []byte(text)}}
+
+	case *ast.DeclStmt:
+		spec := sliceDecl.spec
+		if len(spec.Values) > 0 {
+			// var x = []T{}
+			text, ok := makeText(sliceDecl)
+			if !ok {
+				return fix, false
+			}
+			value := spec.Values[sliceDecl.index]
+			fix.TextEdits = []analysis.TextEdit{{Pos: value.Pos(), End: value.End(), NewText: []byte(text)}}
+			break
+		}
+
+		// var x []T
+		genD := s.Decl.(*ast.GenDecl)
+		var node ast.Node = s
+		if genD.Lparen.IsValid() {
+			// rewrite just the spec when it is part of a grouped declaration
+			node = spec
+		}
+		text, ok := v.declText(spec, genD.Lparen.IsValid(), v.pass.Fset.Position(node.Pos()).Column)
+		if !ok {
+			return fix, false
+		}
+		fix.TextEdits = []analysis.TextEdit{{Pos: node.Pos(), End: node.End(), NewText: []byte(text)}}
+
+	default:
+		return fix, false
+	}
+
+	// the rewrite of a var spec includes every name in it
+	capExprs := []ast.Expr{sliceDecl.capExpr}
+	if spec := sliceDecl.spec; spec != nil && len(spec.Values) == 0 {
+		for _, name := range spec.Names {
+			if other := v.findDeclaration(spec, name.Name); other != nil && other.capExpr != nil {
+				capExprs = append(capExprs, other.capExpr)
+			}
+		}
+	}
+	edits, ok := v.importEdits(sliceDecl.pos, capExprs...)
+	if !ok {
+		return fix, false
+	}
+	fix.TextEdits = append(fix.TextEdits, edits...)
+
+	return fix, true
+}
+
+// declText rewrites a zero value var spec, splitting out each slice that can be
+// preallocated into its own make declaration. The rewrite considers every name
+// in the spec, so multiple diagnostics against the same spec yield identical edits.
+func (v *returnsVisitor) declText(spec *ast.ValueSpec, grouped bool, column int) (string, bool) {
+	typeText, ok := exprText(spec.Type)
+	if !ok {
+		return "", false
+	}
+
+	var lines, remaining []string
+	flush := func() {
+		if len(remaining) == 0 {
+			return
+		}
+		line := strings.Join(remaining, ", ") + " " + typeText
+		if !grouped {
+			line = "var " + line
+		}
+		lines = append(lines, line)
+		remaining = nil
+	}
+
+	for _, name := range spec.Names {
+		sliceDecl := v.findDeclaration(spec, name.Name)
+		if sliceDecl == nil || !sliceDecl.eligible || sliceDecl.ineligible != "" || sliceDecl.move ||
+			sliceDecl.perIteration != nil || sliceDecl.capExpr == nil || sliceDecl.capExpr == invalid ||
+			v.keepsNil(sliceDecl) {
+			remaining = append(remaining, name.Name)
+			continue
+		}
+		text, ok := makeText(sliceDecl)
+		if !ok {
+			return "", false
+		}
+		flush()
+		if grouped {
+			lines = append(lines, name.Name+" = "+text)
+		} else {
+			lines = append(lines, name.Name+" := "+text)
+		}
+	}
+	flush()
+
+	return strings.Join(lines, "\n"+strings.Repeat("\t", column-1)), true
+}
+
+// suggestMove builds a fix that removes the declaration of the slice and
+// makes it just before the first loop appending to it instead.
+func (v *returnsVisitor) suggestMove(sliceDecl *sliceDeclaration) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Move " + sliceDecl.name + " down to the loop and preallocate it"}
+
+	text, ok := makeText(sliceDecl)
+	if !ok {
+		return fix, false
+	}
+	column := v.pass.Fset.Position(sliceDecl.loop.Pos()).Column
+	text = sliceDecl.name + " := " + text + "\n" + strings.Repeat("\t", column-1)
+
+	edits, ok := v.importEdits(sliceDecl.pos, sliceDecl.capExpr)
+	if !ok {
+		return fix, false
+	}
+
+	fix.TextEdits = append([]analysis.TextEdit{
+		{Pos: sliceDecl.stmt.Pos(), End: v.stmtEnd(sliceDecl.stmt, sliceDecl.list)},
+		{Pos: sliceDecl.loop.Pos(), End: sliceDecl.loop.Pos(), NewText: []byte(text)},
+	}, edits...)
+	return fix, true
+}
+
+// suggestGrow builds a fix that grows the builder just before the first loop
+// writing to it.
+func (v *returnsVisitor) suggestGrow(sliceDecl *sliceDeclaration) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Grow " + sliceDecl.name + " before the loop"}
+
+	text, ok := growText(sliceDecl)
+	if !ok {
+		return fix, false
+	}
+	column := v.pass.Fset.Position(sliceDecl.loop.Pos()).Column
+	text += "\n" + strings.Repeat("\t", column-1)
+
+	edits, ok := v.importEdits(sliceDecl.pos, sliceDecl.capExpr)
+	if !ok {
+		return fix, false
+	}
+
+	fix.TextEdits = append([]analysis.TextEdit{
+		{Pos: sliceDecl.loop.Pos(), End: sliceDecl.loop.Pos(), NewText: []byte(text)},
+	}, edits...)
+	return fix, true
+}
+
+// suggestField builds a fix that makes the nil field of a struct, or a named
+// result, just before the first loop appending to it, or at the start of the
+// function for a named result.
+func (v *returnsVisitor) suggestField(sliceDecl *sliceDeclaration) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Make " + sliceDecl.name + " before the loop"}
+	at := sliceDecl.loop
+	if sliceDecl.entry != nil {
+		fix.Message, at = "Preallocate "+sliceDecl.name, sliceDecl.entry
+	}
+
+	if sliceDecl.typeExpr == nil {
+		return fix, false
+	}
+	text, ok := makeText(sliceDecl)
+	if !ok {
+		return fix, false
+	}
+	column := v.pass.Fset.Position(at.Pos()).Column
+	text = sliceDecl.name + " = " + text + "\n" + strings.Repeat("\t", column-1)
+
+	edits, ok := v.importEdits(sliceDecl.pos, sliceDecl.capExpr)
+	if !ok {
+		return fix, false
+	}
+
+	fix.TextEdits = append([]analysis.TextEdit{
+		{Pos: at.Pos(), End: at.Pos(), NewText: []byte(text)},
+	}, edits...)
+	return fix, true
+}
+
+// stmtEnd returns the end of the text to remove along with a statement,
+// including the rest of its line unless a comment follows on the same line.
+func (v *returnsVisitor) stmtEnd(stmt ast.Stmt, list []ast.Stmt) token.Pos {
+	line := v.pass.Fset.Position(stmt.End()).Line
+	for _, group := range v.file(stmt.Pos()).Comments {
+		if group.Pos() >= stmt.End() && v.pass.Fset.Position(group.Pos()).Line == line {
+			return group.Pos()
+		}
+	}
+	for i, s := range list {
+		if s == stmt && i+1 < len(list) {
+			return list[i+1].Pos()
+		}
+	}
+	return stmt.End()
+}
+
+// file returns the file containing pos.
+func (v *returnsVisitor) file(pos token.Pos) *ast.File {
+	for _, f := range v.pass.Files {
+		if pos >= f.FileStart && pos < f.FileEnd {
+			return f
+		}
+	}
+	return nil
+}
+
+// imports are the packages that capacity expressions may refer to.
+var imports = []struct {
+	pkg  *ast.Ident
+	path string
+}{
+	{bitsPkg, "math/bits"},
+	{slicesPkg, "slices"},
+	{utf8Pkg, "unicode/utf8"},
+}
+
+// importEdits returns the edits importing the packages that the capacity
+// expressions refer to into the file containing pos, if not already imported.
+func (v *returnsVisitor) importEdits(pos token.Pos, capExprs ...ast.Expr) ([]analysis.TextEdit, bool) {
+	var edits []analysis.TextEdit
+	for _, imp := range imports {
+		for _, capExpr := range capExprs {
+			if capExpr == nil || !usesPkg(capExpr, imp.pkg) {
+				continue
+			}
+			edit, ok := v.importEdit(pos, imp.path, imp.pkg.Name)
+			if !ok {
+				return nil, false
+			}
+			edits = append(edits, edit...)
+			break
+		}
+	}
+	return edits, true
+}
+
+// importEdit returns an edit adding an import of importPath, under its default name,
+// to the file containing pos, reporting false if that name is taken.
+func (v *returnsVisitor) importEdit(pos token.Pos, importPath, name string) ([]analysis.TextEdit, bool) {
+	file := v.file(pos)
+	if file == nil {
+		return nil, false
+	}
+	for _, spec := range file.Imports {
+		specPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, false
+		}
+		if specPath == importPath {
+			return nil, spec.Name == nil || spec.Name.Name == name
+		}
+		if (spec.Name != nil && spec.Name.Name == name) || (spec.Name == nil && path.Base(specPath) == name) {
+			return nil, false
+		}
+	}
+
+	text := strconv.Quote(importPath)
+	for _, decl := range file.Decls {
+		genD, ok := decl.(*ast.GenDecl)
+		if !ok || genD.Tok != token.IMPORT {
+			break
+		}
+		if genD.Lparen.IsValid() {
+			return []analysis.TextEdit{{Pos: genD.Rparen, End: genD.Rparen, NewText: []byte("\t" + text + "\n")}}, true
+		}
+		return []analysis.TextEdit{{Pos: genD.End(), End: genD.End(), NewText: []byte("\n\nimport " + text)}}, true
+	}
+	return []analysis.TextEdit{{Pos: file.Name.End(), End: file.Name.End(), NewText: []byte("\n\nimport " + text)}}, true
+}
+
+func (v *returnsVisitor) findDeclaration(spec *ast.ValueSpec, name string) *sliceDeclaration {
+	for _, sliceDecl := range v.sliceDeclarations {
+		if sliceDecl.spec == spec && sliceDecl.name == name {
+			return sliceDecl
+		}
+	}
+	return nil
+}
+
+// makeText formats a make call for the declared type with the computed
+// capacity, or size hint for a map.
+func makeText(sliceDecl *sliceDeclaration) (string, bool) {
+	args := []ast.Expr{sliceDecl.typeExpr, &ast.BasicLit{Kind: token.INT, Value: "0"}, sliceDecl.capExpr}
+	if sliceDecl.kind == mapKind {
+		args = []ast.Expr{sliceDecl.typeExpr, sliceDecl.capExpr}
+	}
+	return exprText(&ast.CallExpr{Fun: ast.NewIdent("make"), Args: args})
+}
+
+// growText formats a call growing the builder by the computed size.
+func growText(sliceDecl *sliceDeclaration) (string, bool) {
+	return exprText(&ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(sliceDecl.name), Sel: ast.NewIdent("Grow")},
+		Args: []ast.Expr{sliceDecl.capExpr},
+	})
+}
+
+func exprText(expr ast.Expr) (string, bool) {
+	buf := bytes.NewBuffer(nil)
+	if format.Node(buf, token.NewFileSet(), expr) != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
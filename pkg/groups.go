@@ -0,0 +1,332 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// groupAppend is an append to the slice stored under a key of a map, e.g.
+// `m[k] = append(m[k], v)`, which grows a slice per key.
+type groupAppend struct {
+	assign *ast.AssignStmt
+	obj    types.Object // map holding the slices
+	name   string
+	index  *ast.IndexExpr // slice appended to
+	call   *ast.CallExpr
+}
+
+// groupBy reports the maps of slices that a range loop groups elements into,
+// when the map is made empty just before the loop.
+func (v *returnsVisitor) groupBy(loopStmt ast.Stmt, loop *ast.RangeStmt) {
+	seen := make(map[types.Object]bool)
+	for _, group := range v.groupAppends(loop.Body) {
+		if seen[group.obj] {
+			continue
+		}
+		seen[group.obj] = true
+
+		decl := v.freshMap(group.obj, loopStmt)
+		if decl == nil {
+			continue
+		}
+		diag := analysis.Diagnostic{
+			Pos:      decl.Pos(),
+			Category: CategorySlice,
+			Message: "Consider preallocating the slices grouped in " + group.name +
+				" using a counting pre-pass, or by partitioning a single backing slice by key",
+		}
+		if fix, ok := v.suggestCount(group, loopStmt, loop); ok {
+			diag.SuggestedFixes = []analysis.SuggestedFix{fix}
+		}
+		v.preallocHints = append(v.preallocHints, diag)
+		if sliceDecl := v.findSlice(group.obj); sliceDecl != nil && sliceDecl.kind == mapKind && sliceDecl.ineligible == "" {
+			// reported once, as grouping rather than as a map to size
+			sliceDecl.ineligible = "map of grouped slices"
+		}
+	}
+}
+
+// groupAppends returns the appends to slices under the keys of maps within
+// the body of a loop, outside of any nested loop or closure.
+func (v *returnsVisitor) groupAppends(body *ast.BlockStmt) []groupAppend {
+	var groups []groupAppend
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit, *ast.ForStmt, *ast.RangeStmt:
+			return false
+		case *ast.AssignStmt:
+			if n.Tok != token.ASSIGN || len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+				return false
+			}
+			if group, ok := v.groupAppend(n); ok {
+				groups = append(groups, group)
+			}
+			return false
+		}
+		return true
+	})
+	return groups
+}
+
+// groupAppend reports whether an assignment appends to the slice under a key
+// of a map of slices, e.g. `m[k] = append(m[k], v)`.
+func (v *returnsVisitor) groupAppend(assign *ast.AssignStmt) (groupAppend, bool) {
+	lhs, ok := ast.Unparen(assign.Lhs[0]).(*ast.IndexExpr)
+	if !ok {
+		return groupAppend{}, false
+	}
+	obj := v.mapInsert(lhs)
+	if obj == nil || !v.isSlice(lhs) {
+		return groupAppend{}, false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok || !v.isBuiltin(call.Fun, "append") || len(call.Args) < 2 {
+		return groupAppend{}, false
+	}
+	rhs, ok := ast.Unparen(call.Args[0]).(*ast.IndexExpr)
+	if !ok || v.mapInsert(rhs) != obj {
+		return groupAppend{}, false
+	}
+	lhsKey, ok := exprText(lhs.Index)
+	if !ok {
+		return groupAppend{}, false
+	}
+	if rhsKey, ok := exprText(rhs.Index); !ok || rhsKey != lhsKey {
+		return groupAppend{}, false
+	}
+	return groupAppend{assign: assign, obj: obj, name: obj.Name(), index: lhs, call: call}, true
+}
+
+// freshMap returns the statement just before the loop, in the same statement
+// list, that makes the given map empty, or nil if there is none or the map is
+// used in between.
+func (v *returnsVisitor) freshMap(obj types.Object, loopStmt ast.Stmt) ast.Stmt {
+	loopIndex := -1
+	for i, stmt := range v.list {
+		if stmt == loopStmt {
+			loopIndex = i
+		}
+	}
+	for i := loopIndex - 1; i >= 0; i-- {
+		stmt := v.list[i]
+		if v.makesEmptyMap(stmt, obj) {
+			return stmt
+		}
+		referenced := false
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && v.pass.TypesInfo.ObjectOf(ident) == obj {
+				referenced = true
+			}
+			return !referenced
+		})
+		if referenced {
+			return nil
+		}
+	}
+	return nil
+}
+
+// makesEmptyMap reports whether stmt assigns an empty map to the given variable,
+// e.g. `m := make(map[K][]V)` or `var m = map[K][]V{}`.
+func (v *returnsVisitor) makesEmptyMap(stmt ast.Stmt, obj types.Object) bool {
+	var names []*ast.Ident
+	var values []ast.Expr
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range s.Lhs {
+			ident, _ := lhs.(*ast.Ident)
+			names = append(names, ident)
+		}
+		values = s.Rhs
+	case *ast.DeclStmt:
+		genD, ok := s.Decl.(*ast.GenDecl)
+		if !ok || genD.Tok != token.VAR || len(genD.Specs) != 1 {
+			return false
+		}
+		spec := genD.Specs[0].(*ast.ValueSpec)
+		names, values = spec.Names, spec.Values
+	default:
+		return false
+	}
+	if len(names) != len(values) {
+		return false
+	}
+	for i, name := range names {
+		if name == nil || v.pass.TypesInfo.ObjectOf(name) != obj {
+			continue
+		}
+		if call, ok := values[i].(*ast.CallExpr); ok && len(call.Args) == 2 && v.isBuiltin(call.Fun, "make") {
+			// e.g., `make(map[K][]V, n)`
+			return v.isMap(call.Args[0])
+		}
+		_, lenExpr, ok := v.isCreateMap(values[i])
+		return ok && lenExpr == nil
+	}
+	return false
+}
+
+// suggestCount builds a fix that counts the elements appended under each key
+// in a pre-pass over the same range, then makes each slice with its count.
+// The loop must do nothing but append a known number of elements.
+func (v *returnsVisitor) suggestCount(group groupAppend, loopStmt ast.Stmt, loop *ast.RangeStmt) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Count the elements grouped in " + group.name + " in a pre-pass"}
+
+	if len(loop.Body.List) != 1 || loop.Body.List[0] != group.assign || loop.Tok == token.ASSIGN || !isPath(group.index.Index) {
+		return fix, false
+	}
+	switch t := coreType(v.pass.TypesInfo.TypeOf(loop.X)).(type) {
+	case *types.Slice, *types.Array, *types.Map:
+	case *types.Pointer:
+		if _, ok := coreType(t.Elem()).(*types.Array); !ok {
+			return fix, false
+		}
+	case *types.Basic:
+		if t.Info()&(types.IsInteger|types.IsString) == 0 {
+			return fix, false
+		}
+	default:
+		// channels and iterators may not be ranged over twice
+		return fix, false
+	}
+	if !isPath(loop.X) {
+		if tv := v.pass.TypesInfo.Types[loop.X]; tv.Value == nil {
+			return fix, false
+		}
+	}
+
+	var n ast.Expr
+	if group.call.Ellipsis.IsValid() {
+		if n = v.spreadLen(group.call.Args[1]); n == nil {
+			return fix, false
+		}
+	} else {
+		n = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(group.call.Args) - 1)}
+	}
+
+	mapType, ok := coreType(v.pass.TypesInfo.TypeOf(group.index.X)).(*types.Map)
+	if !ok {
+		return fix, false
+	}
+	keyType, ok := v.typeExprAt(mapType.Key(), loop.Pos())
+	if !ok {
+		return fix, false
+	}
+	sliceType, ok := v.typeExprAt(mapType.Elem(), loop.Pos())
+	if !ok {
+		return fix, false
+	}
+	keyTypeText, _ := exprText(keyType)
+	sliceTypeText, _ := exprText(sliceType)
+	keyText, _ := exprText(group.index.Index)
+	nText, ok := exprText(n)
+	if !ok {
+		return fix, false
+	}
+	header, ok := exprText(loop.X)
+	if !ok {
+		return fix, false
+	}
+	header = "range " + header
+	// the pre-pass only declares the range variables that the count refers to
+	used := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		if !ok || ident.Name == "_" {
+			return false
+		}
+		obj := v.pass.TypesInfo.ObjectOf(ident)
+		refers := false
+		for _, counted := range []ast.Expr{group.index.Index, n} {
+			ast.Inspect(counted, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && v.pass.TypesInfo.ObjectOf(id) == obj {
+					refers = true
+				}
+				return !refers
+			})
+		}
+		return refers
+	}
+	switch key, value := used(loop.Key), used(loop.Value); {
+	case value:
+		keyName := "_"
+		if key {
+			keyName = loop.Key.(*ast.Ident).Name
+		}
+		header = keyName + ", " + loop.Value.(*ast.Ident).Name + " := " + header
+	case key:
+		header = loop.Key.(*ast.Ident).Name + " := " + header
+	}
+
+	// names for the counts and the variables ranging over them
+	var taken []string
+	for _, ident := range []ast.Expr{loop.Key, loop.Value} {
+		if ident, ok := ident.(*ast.Ident); ok {
+			taken = append(taken, ident.Name)
+		}
+	}
+	counts, ok := v.freeName("counts", loopStmt.Pos(), taken)
+	if !ok {
+		return fix, false
+	}
+	key, ok := v.freeName("key", loopStmt.Pos(), append(taken, counts))
+	if !ok {
+		return fix, false
+	}
+	size, ok := v.freeName("n", loopStmt.Pos(), append(taken, counts, key))
+	if !ok {
+		return fix, false
+	}
+
+	count := counts + "[" + keyText + "]++"
+	if nText != "1" {
+		count = counts + "[" + keyText + "] += " + nText
+	}
+
+	indent := "\n" + strings.Repeat("\t", v.pass.Fset.Position(loopStmt.Pos()).Column-1)
+	text := strings.Join([]string{
+		counts + " := make(map[" + keyTypeText + "]int)",
+		"for " + header + " {",
+		"\t" + count,
+		"}",
+		"for " + key + ", " + size + " := range " + counts + " {",
+		"\t" + group.name + "[" + key + "] = make(" + sliceTypeText + ", 0, " + size + ")",
+		"}",
+		"",
+	}, indent)
+
+	fix.TextEdits = []analysis.TextEdit{{Pos: loopStmt.Pos(), End: loopStmt.Pos(), NewText: []byte(text)}}
+	return fix, true
+}
+
+// freeName returns name, or name with a numeric suffix, such that it refers to
+// nothing at pos nor in the scope enclosing it, and is not one of taken.
+func (v *returnsVisitor) freeName(name string, pos token.Pos, taken []string) (string, bool) {
+	scope := v.pass.Pkg.Scope().Innermost(pos)
+	if scope == nil {
+		return "", false
+	}
+outer:
+	for i := 1; i < 10; i++ {
+		candidate := name
+		if i > 1 {
+			candidate += strconv.Itoa(i)
+		}
+		for _, t := range taken {
+			if t == candidate {
+				continue outer
+			}
+		}
+		if scope.Lookup(candidate) != nil {
+			continue
+		}
+		if _, obj := scope.LookupParent(candidate, pos); obj != nil {
+			continue
+		}
+		return candidate, true
+	}
+	return "", false
+}
@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// slicesPkg refers to the slices package, which the fix imports if needed.
+var slicesPkg = ast.NewIdent("slices")
+
+// declareParams declares the slice parameters of a function, and the slices
+// that pointer parameters point to, which can only be grown before a loop
+// appends to them.
+func (v *returnsVisitor) declareParams(typ *ast.FuncType) {
+	for _, field := range typ.Params.List {
+		for _, name := range field.Names {
+			obj := v.pass.TypesInfo.ObjectOf(name)
+			if obj == nil || name.Name == "_" {
+				continue
+			}
+			sliceDecl := &sliceDeclaration{pos: name.Pos(), fixable: v.allowsGrow(name.Pos()), kind: growKind}
+			if v.isSlice(name) {
+				v.declare(name, sliceDecl)
+			} else if ptr, ok := types.Unalias(obj.Type()).(*types.Pointer); ok && isSliceType(ptr.Elem()) {
+				// e.g., `*out = append(*out, v)`
+				v.declareAt(obj, "*", "*"+name.Name, ptr.Elem(), sliceDecl)
+			}
+		}
+	}
+}
+
+// isGrowable reports whether expr is a slice value that the function did not
+// make itself, such as the result of a call or a reslice, e.g. `buf[:0]`.
+func (v *returnsVisitor) isGrowable(expr ast.Expr) bool {
+	if !v.isSlice(expr) || v.isNil(expr) {
+		return false
+	}
+	if call, ok := expr.(*ast.CallExpr); ok && v.isBuiltin(call.Fun, "append") {
+		// appending keeps the slice, and is counted instead
+		return false
+	}
+	_, _, ok := v.isCreateArray(expr)
+	return !ok
+}
+
+// keepsCapacity reports whether expr empties the slice assigned to ident while
+// keeping the capacity it was made with, e.g. `buf = buf[:0]` after
+// `buf := make([]byte, 0, 64)`, which growing again would not help.
+func (v *returnsVisitor) keepsCapacity(ident *ast.Ident, expr ast.Expr) bool {
+	slice, ok := ast.Unparen(expr).(*ast.SliceExpr)
+	if !ok || slice.High == nil || !isEmptyLen(slice.High) {
+		return false
+	}
+	if x, ok := ast.Unparen(slice.X).(*ast.Ident); !ok || v.pass.TypesInfo.Uses[x] != v.pass.TypesInfo.ObjectOf(ident) {
+		return false
+	}
+	return v.capped[v.pass.TypesInfo.ObjectOf(ident)]
+}
+
+// allowsGrow reports whether the Go version of the file containing pos
+// provides slices.Grow.
+func (v *returnsVisitor) allowsGrow(pos token.Pos) bool {
+	return v.goVersionAtLeast(pos, "go1.21")
+}
+
+// refersToSlices reports whether `slices` refers to the slices package at pos,
+// or to nothing such that it can be imported.
+func (v *returnsVisitor) refersToSlices(pos token.Pos) bool {
+	scope := v.pass.Pkg.Scope().Innermost(pos)
+	if scope == nil {
+		return false
+	}
+	_, obj := scope.LookupParent(slicesPkg.Name, pos)
+	if pkgName, ok := obj.(*types.PkgName); ok {
+		return pkgName.Imported().Path() == "slices"
+	}
+	return obj == nil
+}
+
+// suggestSlicesGrow builds a fix that grows the slice with slices.Grow just
+// before the first loop appending to it.
+func (v *returnsVisitor) suggestSlicesGrow(sliceDecl *sliceDeclaration) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Grow " + sliceDecl.name + " before the loop"}
+
+	if !v.refersToSlices(sliceDecl.loop.Pos()) {
+		return fix, false
+	}
+	text, ok := exprText(&ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: slicesPkg, Sel: ast.NewIdent("Grow")},
+		Args: []ast.Expr{ast.NewIdent(sliceDecl.name), sliceDecl.capExpr},
+	})
+	if !ok {
+		return fix, false
+	}
+	column := v.pass.Fset.Position(sliceDecl.loop.Pos()).Column
+	text = sliceDecl.name + " = " + text + "\n" + strings.Repeat("\t", column-1)
+
+	edits, ok := v.importEdits(sliceDecl.loop.Pos(), slicesPkg, sliceDecl.capExpr)
+	if !ok {
+		return fix, false
+	}
+
+	fix.TextEdits = append([]analysis.TextEdit{
+		{Pos: sliceDecl.loop.Pos(), End: sliceDecl.loop.Pos(), NewText: []byte(text)},
+	}, edits...)
+	return fix, true
+}
+
+// reportGrow reports a slice the function did not make at the first loop
+// appending to it, suggesting growing it by the computed number of elements.
+func (v *returnsVisitor) reportGrow(sliceDecl *sliceDeclaration) {
+	if sliceDecl.perIteration != nil || sliceDecl.capExpr == nil || sliceDecl.capExpr == invalid {
+		return
+	}
+	size, ok := exprText(sliceDecl.capExpr)
+	if !ok {
+		return
+	}
+	if sliceDecl.upperBound {
+		size = "at most " + size
+	}
+
+	diag := analysis.Diagnostic{
+		Pos:      sliceDecl.loop.Pos(),
+		Category: CategorySlice,
+		Message:  "Consider growing " + sliceDecl.name + " by " + size + " before the loop",
+	}
+	if sliceDecl.fixable {
+		diag.Message = "Consider growing " + sliceDecl.name + " by " + size + " with slices.Grow before the loop"
+	}
+	if v.keepsNil(sliceDecl) {
+		// growing a nil slice by an upper bound makes it, even if the loop
+		// appends nothing
+		diag.Message += ", although it is " + sliceDecl.nilUse + " and is no longer nil if empty"
+	} else if sliceDecl.fixable {
+		if fix, ok := v.suggestSlicesGrow(sliceDecl); ok {
+			diag.SuggestedFixes = []analysis.SuggestedFix{fix}
+		}
+	}
+	v.preallocHints = append(v.preallocHints, diag)
+}
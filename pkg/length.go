@@ -0,0 +1,349 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// madeWith returns the make call if expr makes a slice, e.g. `make([]T, n)`.
+func (v *returnsVisitor) madeWith(expr ast.Expr) *ast.CallExpr {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) < 2 || !v.isBuiltin(call.Fun, "make") || !v.isSlice(call.Args[0]) {
+		return nil
+	}
+	return call
+}
+
+// makeCap returns the capacity passed to a make call, e.g. c in `make([]T, n, c)`.
+func makeCap(expr ast.Expr) ast.Expr {
+	if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) == 3 {
+		return call.Args[2]
+	}
+	return nil
+}
+
+// sameLength reports whether two length expressions are evidently equal.
+func sameLength(x, y ast.Expr) bool {
+	if x == nil || y == nil || x == invalid || y == invalid {
+		return false
+	}
+	if xInt, ok := exprIntValue(x); ok {
+		yInt, ok := exprIntValue(y)
+		return ok && xInt == yInt
+	}
+	xText, ok := exprText(x)
+	if !ok {
+		return false
+	}
+	yText, ok := exprText(y)
+	return ok && xText == yText
+}
+
+// isBenchmark reports whether fn is a benchmark in a test file, which may
+// append to a slice made with a length on purpose.
+func (v *returnsVisitor) isBenchmark(fn *ast.FuncDecl) bool {
+	return strings.HasPrefix(fn.Name.Name, "Benchmark") && strings.HasSuffix(v.pass.Fset.Position(fn.Pos()).Filename, "_test.go")
+}
+
+// zeroFilled builds the diagnostic for a slice made with the length of the
+// loop appending to it, which leaves that many zero values before the
+// appended elements. The fixes make the slice empty instead, or assign its
+// elements by index when the loop appends exactly one per iteration.
+func (v *returnsVisitor) zeroFilled(sliceDecl *sliceDeclaration, bounds appendBounds, exits bool) *analysis.Diagnostic {
+	lenExpr := sliceDecl.made.Args[1]
+	lenText, ok := exprText(lenExpr)
+	if !ok {
+		return nil
+	}
+
+	edit := analysis.TextEdit{Pos: lenExpr.Pos(), End: lenExpr.Pos(), NewText: []byte("0, ")}
+	if sliceDecl.capArg != nil {
+		// e.g., `make([]T, n, n)`
+		edit = analysis.TextEdit{Pos: lenExpr.Pos(), End: lenExpr.End(), NewText: []byte("0")}
+	}
+	fixes := []analysis.SuggestedFix{{
+		Message:   "Make " + sliceDecl.name + " with zero length",
+		TextEdits: []analysis.TextEdit{edit},
+	}}
+	if !exits && bounds.min.equal(constCount(1)) && bounds.max.equal(constCount(1)) {
+		if fix, ok := v.suggestIndex(sliceDecl); ok {
+			fixes = append(fixes, fix)
+		}
+	}
+
+	return &analysis.Diagnostic{
+		Pos:            sliceDecl.pos,
+		Category:       CategoryBug,
+		Message:        sliceDecl.name + " is made with length " + lenText + " and then appended to, so it starts with " + lenText + " zero values",
+		SuggestedFixes: fixes,
+	}
+}
+
+// suggestIndex builds a fix that replaces the append made by every iteration
+// of the loop with an assignment at the index of the iteration.
+func (v *returnsVisitor) suggestIndex(sliceDecl *sliceDeclaration) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Assign " + sliceDecl.name + " by index"}
+
+	index, body := v.loopIndex(sliceDecl.loop)
+	if index == nil {
+		return fix, false
+	}
+	indexObj := v.pass.TypesInfo.ObjectOf(index)
+	if v.written(body, map[types.Object]bool{indexObj: true}, nil) != nil {
+		return fix, false
+	}
+
+	for _, stmt := range body.List {
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			continue
+		}
+		call, ok := v.selfAppend(assign.Lhs[0], assign.Rhs[0])
+		if !ok || v.trackedSlice(assign.Lhs[0]) != sliceDecl {
+			continue
+		}
+		if len(call.Args) != 2 || call.Ellipsis.IsValid() {
+			return fix, false
+		}
+
+		// the index must not be shadowed at the append
+		scope := v.pass.Pkg.Scope().Innermost(assign.Pos())
+		if scope == nil {
+			return fix, false
+		}
+		if _, obj := scope.LookupParent(index.Name, assign.Pos()); obj != indexObj {
+			return fix, false
+		}
+
+		elem := call.Args[1]
+		fix.TextEdits = []analysis.TextEdit{
+			{Pos: assign.Pos(), End: elem.Pos(), NewText: []byte(indexable(sliceDecl.name) + "[" + index.Name + "] = ")},
+			{Pos: elem.End(), End: assign.End()},
+		}
+		return fix, true
+	}
+	return fix, false
+}
+
+// loopIndex returns the variable counting the iterations of a loop from zero,
+// along with the loop body, or nil if it has none.
+func (v *returnsVisitor) loopIndex(loopStmt ast.Stmt) (*ast.Ident, *ast.BlockStmt) {
+	switch loop := unlabel(loopStmt).(type) {
+	case *ast.RangeStmt:
+		key, ok := loop.Key.(*ast.Ident)
+		if !ok || key.Name == "_" {
+			return nil, nil
+		}
+		switch t := coreType(v.pass.TypesInfo.TypeOf(loop.X)).(type) {
+		case *types.Slice, *types.Array:
+		case *types.Pointer:
+			if _, ok := coreType(t.Elem()).(*types.Array); !ok {
+				return nil, nil
+			}
+		case *types.Basic:
+			// the keys of a string are byte offsets
+			if t.Info()&types.IsInteger == 0 {
+				return nil, nil
+			}
+		default:
+			return nil, nil
+		}
+		return key, loop.Body
+
+	case *ast.ForStmt:
+		init, ok := loop.Init.(*ast.AssignStmt)
+		if !ok || init.Tok != token.DEFINE || len(init.Lhs) != 1 || len(init.Rhs) != 1 {
+			return nil, nil
+		}
+		if n, ok := exprIntValue(init.Rhs[0]); !ok || n != 0 {
+			return nil, nil
+		}
+		step, ok := parseForLoopStep(loop.Post)
+		if !ok || step.factor != 0 || !step.increasing || !isOne(step.step) {
+			return nil, nil
+		}
+		key, ok := init.Lhs[0].(*ast.Ident)
+		if !ok || key.Name != step.name {
+			return nil, nil
+		}
+		if _, op := forLoopUpperBound(loop.Cond, step.name); op != token.LSS {
+			return nil, nil
+		}
+		return key, loop.Body
+	}
+	return nil, nil
+}
+
+// indexedFirst returns the first index assignment to each in-scope slice
+// within the loop body that precedes any append to or reassignment of it,
+// unless guarded by a check of the slice's length.
+func (v *returnsVisitor) indexedFirst(body *ast.BlockStmt) map[types.Object]*ast.IndexExpr {
+	if len(v.sliceDeclarations) == 0 {
+		return nil
+	}
+	seen := make(map[*sliceDeclaration]bool)
+	indexed := make(map[types.Object]*ast.IndexExpr)
+	var guarded []lengthGuard
+	index := func(expr ast.Expr) {
+		e, ok := ast.Unparen(expr).(*ast.IndexExpr)
+		if !ok {
+			return
+		}
+		sliceDecl := v.trackedSlice(e.X)
+		if sliceDecl == nil || seen[sliceDecl] || !v.isSlice(e.X) {
+			return
+		}
+		for _, guard := range guarded {
+			if guard.sliceDecl == sliceDecl && guard.node.Pos() <= e.Pos() && e.End() <= guard.node.End() {
+				// e.g., `if i < len(x) { x[i] = v }`
+				return
+			}
+		}
+		seen[sliceDecl] = true
+		indexed[sliceDecl.obj] = e
+	}
+	guard := func(cond ast.Expr, node ast.Node) {
+		for _, sliceDecl := range v.lengthChecked(cond) {
+			guarded = append(guarded, lengthGuard{sliceDecl: sliceDecl, node: node})
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			guard(n.Cond, n)
+		case *ast.ForStmt:
+			guard(n.Cond, n)
+		case *ast.RangeStmt:
+			if sliceDecl := v.trackedSlice(n.X); sliceDecl != nil {
+				guarded = append(guarded, lengthGuard{sliceDecl: sliceDecl, node: n})
+			}
+		case *ast.SwitchStmt:
+			guard(n.Tag, n)
+		case *ast.CaseClause:
+			for _, expr := range n.List {
+				guard(expr, n)
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if sliceDecl := v.trackedSlice(lhs); sliceDecl != nil {
+					// appended to or reassigned
+					seen[sliceDecl] = true
+				} else {
+					index(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			index(n.X)
+		}
+		return true
+	})
+	return indexed
+}
+
+// lengthGuard is a statement whose body only runs within the length of a slice,
+// as checked by its condition.
+type lengthGuard struct {
+	sliceDecl *sliceDeclaration
+	node      ast.Node
+}
+
+// lengthChecked returns the declarations of the in-scope slices whose length
+// or capacity expr refers to, e.g. `i < len(x)`.
+func (v *returnsVisitor) lengthChecked(expr ast.Expr) []*sliceDeclaration {
+	if expr == nil {
+		return nil
+	}
+	var sliceDecls []*sliceDeclaration
+	ast.Inspect(expr, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 || (!v.isBuiltin(call.Fun, "len") && !v.isBuiltin(call.Fun, "cap")) {
+			return true
+		}
+		if sliceDecl := v.trackedSlice(call.Args[0]); sliceDecl != nil {
+			sliceDecls = append(sliceDecls, sliceDecl)
+		}
+		return true
+	})
+	return sliceDecls
+}
+
+// indexedEmpty records a bug for each slice that is empty when first assigned
+// by index in the loop, which panics with index out of range.
+func (v *returnsVisitor) indexedEmpty(indexed map[types.Object]*ast.IndexExpr, loopStmt ast.Stmt, countExpr ast.Expr) {
+	for obj, index := range indexed {
+		sliceDecl := v.findSlice(obj)
+		if sliceDecl == nil || sliceDecl.kind != sliceKind || sliceDecl.loop != nil || sliceDecl.pending != nil ||
+			sliceDecl.touched != "" || sliceDecl.ineligible != "" {
+			continue
+		}
+		if !sliceDecl.fixable && (sliceDecl.made == nil || !isEmptyLen(sliceDecl.made.Args[1])) {
+			// made with a non-zero length
+			continue
+		}
+
+		sliceDecl.loop = loopStmt
+		diag := &analysis.Diagnostic{
+			Pos:      sliceDecl.pos,
+			Category: CategoryBug,
+			Message:  sliceDecl.name + " has length 0 when assigned by index in the loop, which panics with index out of range",
+		}
+		if fix, ok := v.suggestLength(sliceDecl, index, countExpr); ok {
+			diag.SuggestedFixes = []analysis.SuggestedFix{fix}
+		}
+		sliceDecl.bug = diag
+	}
+}
+
+// suggestLength builds a fix that makes the slice with the length of the loop
+// assigning to it by index, when the index is the iteration of the loop.
+func (v *returnsVisitor) suggestLength(sliceDecl *sliceDeclaration, index *ast.IndexExpr, countExpr ast.Expr) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Make " + sliceDecl.name + " with the length of the loop"}
+	if countExpr == nil || countExpr == invalid {
+		return fix, false
+	}
+
+	loopIndex, _ := v.loopIndex(sliceDecl.loop)
+	ident, ok := ast.Unparen(index.Index).(*ast.Ident)
+	if loopIndex == nil || !ok || v.pass.TypesInfo.ObjectOf(ident) != v.pass.TypesInfo.ObjectOf(loopIndex) {
+		return fix, false
+	}
+
+	var value ast.Expr
+	switch s := sliceDecl.stmt.(type) {
+	case *ast.AssignStmt:
+		value = s.Rhs[sliceDecl.index]
+	case *ast.DeclStmt:
+		if len(sliceDecl.spec.Values) == 0 {
+			return fix, false
+		}
+		value = sliceDecl.spec.Values[sliceDecl.index]
+	default:
+		return fix, false
+	}
+
+	lenExpr, ok := v.capacityAt(countExpr, sliceDecl.pos)
+	if !ok {
+		return fix, false
+	}
+	args := []ast.Expr{sliceDecl.typeExpr, lenExpr}
+	if sliceDecl.capArg != nil && !sameLength(sliceDecl.capArg, lenExpr) {
+		args = append(args, sliceDecl.capArg)
+	}
+	text, ok := exprText(&ast.CallExpr{Fun: ast.NewIdent("make"), Args: args})
+	if !ok {
+		return fix, false
+	}
+	edits, ok := v.importEdits(sliceDecl.pos, lenExpr)
+	if !ok {
+		return fix, false
+	}
+	fix.TextEdits = append([]analysis.TextEdit{{Pos: value.Pos(), End: value.End(), NewText: []byte(text)}}, edits...)
+	return fix, true
+}
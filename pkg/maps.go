@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// isCreateMap reports whether expr creates a new map without a size hint,
+// returning the type of the map and its initial number of entries (nil when empty).
+func (v *returnsVisitor) isCreateMap(expr ast.Expr) (ast.Expr, ast.Expr, bool) {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		// map[K]V{...}
+		if e.Type == nil || !v.isMap(e) {
+			return nil, nil, false
+		}
+		if len(e.Elts) > 0 {
+			return e.Type, &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(e.Elts))}, true
+		}
+		return e.Type, nil, true
+	case *ast.CallExpr:
+		// make(map[K]V)
+		if len(e.Args) != 1 || !v.isBuiltin(e.Fun, "make") || !v.isMap(e.Args[0]) {
+			return nil, nil, false
+		}
+		return e.Args[0], nil, true
+	}
+	return nil, nil, false
+}
+
+func (v *returnsVisitor) isMap(expr ast.Expr) bool {
+	_, ok := coreType(v.pass.TypesInfo.TypeOf(expr)).(*types.Map)
+	return ok
+}
+
+// mapInsert returns the map variable that writing to expr may insert an entry
+// into, e.g. `m[k] = v` or `m[k]++`, or nil if there is none.
+func (v *returnsVisitor) mapInsert(expr ast.Expr) types.Object {
+	index, ok := ast.Unparen(expr).(*ast.IndexExpr)
+	if !ok || !v.isMap(index.X) {
+		return nil
+	}
+	ident, ok := ast.Unparen(index.X).(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	return v.pass.TypesInfo.Uses[ident]
+}
+
+// distinctKeys returns the variables that take a different value in every
+// iteration of the loop, such that a map insert keyed by one of them adds an
+// entry every time: the key of a range loop over anything but a channel or
+// function, or the variable that a for loop steps.
+func (v *returnsVisitor) distinctKeys(loopStmt ast.Stmt) map[types.Object]bool {
+	var key *ast.Ident
+	switch loop := unlabel(loopStmt).(type) {
+	case *ast.RangeStmt:
+		switch coreType(v.pass.TypesInfo.TypeOf(loop.X)).(type) {
+		case *types.Chan, *types.Signature:
+			return nil
+		}
+		key, _ = loop.Key.(*ast.Ident)
+	case *ast.ForStmt:
+		header := loop
+		if loop.Init == nil && loop.Post == nil {
+			if header = v.whileLoop(loop); header == nil {
+				return nil
+			}
+		}
+		step, ok := parseForLoopStep(header.Post)
+		if !ok {
+			return nil
+		}
+		key = step.ident
+	}
+	if key == nil {
+		return nil
+	}
+	obj := v.pass.TypesInfo.ObjectOf(key)
+	if obj == nil {
+		return nil
+	}
+	return map[types.Object]bool{obj: true}
+}
+
+// foldInsert adds an entry inserted into an in-scope map outside of any loop
+// to the size hint of the next loop inserting into it, or of the last one.
+func (v *returnsVisitor) foldInsert(expr ast.Expr) {
+	obj := v.mapInsert(expr)
+	if obj == nil {
+		return
+	}
+	sliceDecl := v.findSlice(obj)
+	if sliceDecl == nil || sliceDecl.kind != mapKind || sliceDecl.touched != "" {
+		return
+	}
+	sliceDecl.pending = exprIntAdd(sliceDecl.pending, &ast.BasicLit{Kind: token.INT, Value: "1"})
+	if v.depth > sliceDecl.depth {
+		// the insert may not happen
+		sliceDecl.pendingUpperBound = true
+	}
+}
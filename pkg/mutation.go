@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// loopCountMutated returns why the iteration count of the loop cannot be
+// trusted, as its body writes to the loop variable, to a variable the
+// condition depends on, or to the map being ranged over.
+func (v *returnsVisitor) loopCountMutated(loopStmt ast.Stmt) string {
+	switch loop := unlabel(loopStmt).(type) {
+	case *ast.ForStmt:
+		if loop.Cond == nil {
+			// e.g., `for i := 0; ; i++`, which only exits by branching
+			return ""
+		}
+		header := loop
+		if loop.Init == nil && loop.Post == nil {
+			if header = v.whileLoop(loop); header == nil {
+				return ""
+			}
+		}
+		step, ok := parseForLoopStep(header.Post)
+		if !ok {
+			return ""
+		}
+		counter := v.pass.TypesInfo.ObjectOf(step.ident)
+
+		// the condition is evaluated before every iteration
+		objs := v.referencedVars(loop.Cond)
+		obj := v.written(loop.Body, objs, header.Post)
+		switch {
+		case obj == nil:
+			return ""
+		case obj == counter:
+			return "loop variable modified in the loop body"
+		}
+		return "loop bound modified in the loop body"
+
+	case *ast.RangeStmt:
+		if _, ok := coreType(v.pass.TypesInfo.TypeOf(loop.X)).(*types.Map); !ok {
+			// the range expression is evaluated once
+			return ""
+		}
+		if v.written(loop.Body, v.referencedVars(loop.X), nil) != nil {
+			return "ranged map modified in the loop body"
+		}
+	}
+	return ""
+}
+
+// referencedVars returns the variables and fields referred to within expr.
+func (v *returnsVisitor) referencedVars(expr ast.Expr) map[types.Object]bool {
+	objs := make(map[types.Object]bool)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if obj, ok := v.pass.TypesInfo.Uses[ident].(*types.Var); ok {
+				objs[obj] = true
+			}
+		}
+		return true
+	})
+	return objs
+}
+
+// written returns the first of the given objects written to within node,
+// other than by the except statement, or nil if there is none.
+func (v *returnsVisitor) written(node ast.Node, objs map[types.Object]bool, except ast.Stmt) types.Object {
+	var found types.Object
+	check := func(expr ast.Expr) {
+		if obj := v.writtenObj(expr); found == nil && obj != nil && objs[obj] {
+			found = obj
+		}
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found != nil || (except != nil && n == except) {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				for _, lhs := range n.Lhs {
+					check(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			check(n.X)
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				check(n.X)
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				check(n.Key)
+				check(n.Value)
+			}
+		case *ast.CallExpr:
+			if (v.isBuiltin(n.Fun, "delete") || v.isBuiltin(n.Fun, "clear")) && len(n.Args) > 0 {
+				check(n.Args[0])
+			}
+			if tv := v.pass.TypesInfo.Types[n.Fun]; tv.IsBuiltin() || tv.IsType() {
+				break
+			}
+			// the function may write through a pointer or insert into a map
+			for _, arg := range n.Args {
+				if v.isReference(arg) {
+					check(arg)
+				}
+			}
+		case *ast.SelectorExpr:
+			// a method with a pointer receiver, or of a map, may write to its receiver
+			if sel, ok := v.pass.TypesInfo.Selections[n]; ok && sel.Kind() == types.MethodVal {
+				recv := sel.Obj().(*types.Func).Type().(*types.Signature).Recv()
+				if _, ok := types.Unalias(recv.Type()).(*types.Pointer); ok || v.isReference(n.X) {
+					check(n.X)
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// isReference reports whether expr is a pointer or map, through which a
+// function it is passed to may write.
+func (v *returnsVisitor) isReference(expr ast.Expr) bool {
+	switch coreType(v.pass.TypesInfo.TypeOf(expr)).(type) {
+	case *types.Pointer, *types.Map:
+		return true
+	}
+	return false
+}
+
+// writtenObj returns the variable or field whose value, or map length, is
+// changed by writing to expr, including the pointer written through, e.g. `p`
+// for `*p = append(*p, v)`.
+func (v *returnsVisitor) writtenObj(expr ast.Expr) types.Object {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return v.pass.TypesInfo.ObjectOf(e)
+	case *ast.SelectorExpr:
+		return v.pass.TypesInfo.ObjectOf(e.Sel)
+	case *ast.StarExpr:
+		return v.writtenObj(e.X)
+	case *ast.IndexExpr:
+		// writing to a map can change its length, and to an array its
+		// elements, while a slice shares them with others
+		switch t := coreType(v.pass.TypesInfo.TypeOf(e.X)).(type) {
+		case *types.Map, *types.Array:
+			return v.writtenObj(e.X)
+		case *types.Pointer:
+			if _, ok := coreType(t.Elem()).(*types.Array); ok {
+				return v.writtenObj(e.X)
+			}
+		}
+	}
+	return nil
+}
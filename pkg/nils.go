@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// nilUses records the uses of in-scope slices within node, after a loop has
+// appended to them, that tell a nil slice from an empty one. Making the slice
+// before the loop changes what these uses observe when the loop appends nothing.
+func (v *returnsVisitor) nilUses(node ast.Node) {
+	if node == nil || len(v.sliceDeclarations) == 0 {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+
+		case *ast.BinaryExpr:
+			if n.Op != token.EQL && n.Op != token.NEQ {
+				break
+			}
+			if v.isNil(n.Y) {
+				v.nilUse(v.within(n.X), "compared to nil")
+			} else if v.isNil(n.X) {
+				v.nilUse(v.within(n.Y), "compared to nil")
+			}
+
+		case *ast.CallExpr:
+			if v.isMarshal(n) {
+				for _, arg := range n.Args {
+					v.nilUse(v.held(arg), "marshalled, where a nil slice encodes as null")
+				}
+			}
+
+		case *ast.ReturnStmt:
+			if !v.exported {
+				break
+			}
+			if len(n.Results) == 0 {
+				// a bare return of the named results
+				v.nilUse(v.results, "returned from an exported function")
+			}
+			for _, result := range n.Results {
+				v.nilUse(v.held(result), "returned from an exported function")
+			}
+		}
+		return true
+	})
+}
+
+// nilUse records the use of each slice that a loop has appended to.
+func (v *returnsVisitor) nilUse(sliceDecls []*sliceDeclaration, reason string) {
+	for _, sliceDecl := range sliceDecls {
+		if sliceDecl.loop != nil && sliceDecl.nilUse == "" {
+			sliceDecl.nilUse = reason
+		}
+	}
+}
+
+// held returns the declarations of the in-scope slices that expr refers to or
+// selects from, or that a composite literal holds, e.g. `Page{Items: x}`.
+func (v *returnsVisitor) held(expr ast.Expr) []*sliceDeclaration {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.CompositeLit:
+		var sliceDecls []*sliceDeclaration
+		for _, elt := range e.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			sliceDecls = append(sliceDecls, v.held(elt)...)
+		}
+		return sliceDecls
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return v.held(e.X)
+		}
+	}
+	return v.within(expr)
+}
+
+// isMarshal reports whether call encodes its arguments as JSON or YAML, e.g.
+// `json.Marshal(x)` or `enc.Encode(x)`.
+func (v *returnsVisitor) isMarshal(call *ast.CallExpr) bool {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return false
+	}
+	fn, ok := v.pass.TypesInfo.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return false
+	}
+	switch fn.Name() {
+	case "Marshal", "MarshalIndent", "Encode":
+	default:
+		return false
+	}
+	pkgPath := fn.Pkg().Path()
+	return pkgPath == "encoding/json" || strings.Contains(path.Base(pkgPath), "yaml")
+}
+
+// startsNil reports whether the slice is nil until made, unlike a preallocated
+// one, or may be for a slice the function did not make.
+func (v *returnsVisitor) startsNil(sliceDecl *sliceDeclaration) bool {
+	if sliceDecl.implicit || sliceDecl.kind == growKind {
+		return true
+	}
+	if spec := sliceDecl.spec; spec != nil {
+		return len(spec.Values) == 0 || v.isNilSlice(spec.Values[sliceDecl.index])
+	}
+	if s, ok := sliceDecl.stmt.(*ast.AssignStmt); ok {
+		return v.isNilSlice(s.Rhs[sliceDecl.index])
+	}
+	return false
+}
+
+// isNilSlice reports whether expr is a nil slice, e.g. `nil` or `[]T(nil)`.
+func (v *returnsVisitor) isNilSlice(expr ast.Expr) bool {
+	if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) == 1 && v.pass.TypesInfo.Types[call.Fun].IsType() {
+		expr = call.Args[0]
+	}
+	return v.isNil(expr)
+}
+
+// keepsNil reports whether preallocating the slice would change what a use
+// after the loop observes, such that it must remain nil when the loop appends
+// nothing. Growing a slice by an exact count leaves it unchanged when the
+// count is zero.
+func (v *returnsVisitor) keepsNil(sliceDecl *sliceDeclaration) bool {
+	if sliceDecl.nilUse == "" || !v.startsNil(sliceDecl) {
+		return false
+	}
+	if _, ok := exprIntValue(sliceDecl.capExpr); ok && !sliceDecl.upperBound {
+		// the slice is never empty after the loop
+		return false
+	}
+	return sliceDecl.kind == sliceKind || (sliceDecl.kind == growKind && sliceDecl.upperBound)
+}
+
+// suggestNonEmpty builds a fix that makes the slice only if the loop appends
+// to it, so that it remains nil otherwise, e.g. `if n > 0 { x = make(...) }`.
+// The slice is made just after its declaration, or at the start of the
+// function or just before the loop if it is a named result or a field.
+func (v *returnsVisitor) suggestNonEmpty(sliceDecl *sliceDeclaration) (analysis.SuggestedFix, bool) {
+	fix := analysis.SuggestedFix{Message: "Preallocate " + sliceDecl.name + " if non-empty"}
+
+	var at ast.Stmt
+	switch {
+	case sliceDecl.implicit && sliceDecl.entry != nil:
+		at = sliceDecl.entry
+	case sliceDecl.implicit, sliceDecl.move:
+		at = sliceDecl.loop
+	default:
+		for i, stmt := range sliceDecl.list {
+			if stmt == sliceDecl.stmt && i+1 < len(sliceDecl.list) {
+				at = sliceDecl.list[i+1]
+			}
+		}
+	}
+	if at == nil || sliceDecl.typeExpr == nil {
+		return fix, false
+	}
+
+	text, ok := makeText(sliceDecl)
+	if !ok {
+		return fix, false
+	}
+	cond, ok := exprText(&ast.BinaryExpr{X: sliceDecl.capExpr, Op: token.GTR, Y: &ast.BasicLit{Kind: token.INT, Value: "0"}})
+	if !ok {
+		return fix, false
+	}
+	indent := "\n" + strings.Repeat("\t", v.pass.Fset.Position(at.Pos()).Column-1)
+	text = "if " + cond + " {" + indent + "\t" + sliceDecl.name + " = " + text + indent + "}" + indent
+
+	edits, ok := v.importEdits(sliceDecl.pos, sliceDecl.capExpr)
+	if !ok {
+		return fix, false
+	}
+
+	fix.TextEdits = append([]analysis.TextEdit{
+		{Pos: at.Pos(), End: at.Pos(), NewText: []byte(text)},
+	}, edits...)
+	return fix, true
+}
@@ -0,0 +1,299 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// pathKey identifies a field, element or pointee selected from a variable,
+// e.g. `res.Rows`, `*out` or `parts[0]`.
+type pathKey struct {
+	root  types.Object
+	steps string
+}
+
+// path returns the variable that expr selects from, along with the steps
+// selecting from it: fields (".f"), dereferences ("*") and constant indices
+// ("[n]"). It reports false if expr is not such a selection.
+func (v *returnsVisitor) path(expr ast.Expr) (types.Object, string, bool) {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		obj, ok := v.pass.TypesInfo.Uses[e].(*types.Var)
+		if !ok || obj.IsField() {
+			return nil, "", false
+		}
+		return obj, "", true
+
+	case *ast.SelectorExpr:
+		sel := v.pass.TypesInfo.Selections[e]
+		if sel == nil || sel.Kind() != types.FieldVal || len(sel.Index()) != 1 {
+			// promoted fields are not supported
+			return nil, "", false
+		}
+		root, steps, ok := v.path(e.X)
+		if !ok {
+			return nil, "", false
+		}
+		if _, isPtr := coreType(v.pass.TypesInfo.TypeOf(e.X)).(*types.Pointer); isPtr {
+			steps += "*"
+		}
+		return root, steps + "." + e.Sel.Name, true
+
+	case *ast.StarExpr:
+		root, steps, ok := v.path(e.X)
+		return root, steps + "*", ok
+
+	case *ast.IndexExpr:
+		n, ok := exprIntValue(e.Index)
+		if !ok {
+			return nil, "", false
+		}
+		root, steps, ok := v.path(e.X)
+		if !ok {
+			return nil, "", false
+		}
+		switch t := coreType(v.pass.TypesInfo.TypeOf(e.X)).(type) {
+		case *types.Array, *types.Slice:
+		case *types.Pointer:
+			if _, ok := coreType(t.Elem()).(*types.Array); !ok {
+				return nil, "", false
+			}
+			steps += "*"
+		default:
+			return nil, "", false
+		}
+		return root, steps + "[" + strconv.Itoa(n) + "]", true
+	}
+	return nil, "", false
+}
+
+// isPathExpr reports whether expr selects from a variable, as described by path.
+func (v *returnsVisitor) isPathExpr(expr ast.Expr) bool {
+	_, _, ok := v.path(expr)
+	return ok
+}
+
+// pathObj returns the object standing for the variable, or the selection from
+// one, that expr refers to, or nil if there is none.
+func (v *returnsVisitor) pathObj(expr ast.Expr) types.Object {
+	root, steps, ok := v.path(expr)
+	if !ok {
+		return nil
+	}
+	return v.pathVar(root, steps, v.pass.TypesInfo.TypeOf(expr))
+}
+
+// pathVar returns the object standing for the steps selecting from root,
+// creating one the first time a selection is seen, so that selections from
+// variables can be tracked like variables themselves.
+func (v *returnsVisitor) pathVar(root types.Object, steps string, t types.Type) types.Object {
+	if steps == "" {
+		return root
+	}
+	key := pathKey{root: root, steps: steps}
+	if obj, ok := v.paths[key]; ok {
+		return obj
+	}
+	if v.paths == nil {
+		v.paths = make(map[pathKey]types.Object)
+	}
+	obj := types.NewVar(root.Pos(), v.pass.Pkg, root.Name()+steps, t)
+	v.paths[key] = obj
+	return obj
+}
+
+// within returns the declarations of the in-scope slices that expr refers to
+// or selects from, e.g. both `res.Rows` and `res.Cols` for `res`.
+func (v *returnsVisitor) within(expr ast.Expr) []*sliceDeclaration {
+	root, steps, ok := v.path(expr)
+	if !ok {
+		return nil
+	}
+	var found []*sliceDeclaration
+	seen := make(map[types.Object]bool)
+	for i := len(v.sliceDeclarations) - 1; i >= 0; i-- {
+		sliceDecl := v.sliceDeclarations[i]
+		if seen[sliceDecl.obj] {
+			// shadowed by a later declaration
+			continue
+		}
+		seen[sliceDecl.obj] = true
+		if sliceDecl.root == root && hasSteps(sliceDecl.steps, steps) {
+			found = append(found, sliceDecl)
+		}
+	}
+	return found
+}
+
+// hasSteps reports whether the steps of a selection begin with prefix.
+func hasSteps(steps, prefix string) bool {
+	if !strings.HasPrefix(steps, prefix) {
+		return false
+	}
+	return len(steps) == len(prefix) || strings.ContainsRune(".*[", rune(steps[len(prefix)]))
+}
+
+// indexable returns the name of the slice in a form that can be indexed.
+func indexable(name string) string {
+	if strings.HasPrefix(name, "*") {
+		return "(" + name + ")"
+	}
+	return name
+}
+
+// isZeroStruct reports whether expr is a zero value struct or pointer to one,
+// returning the slice fields it leaves nil, e.g. `Result{}` or `&Result{Name: n}`.
+func (v *returnsVisitor) isZeroStruct(expr ast.Expr) (string, []*types.Var, bool) {
+	prefix := ""
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		prefix, expr = "*", unary.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok || lit.Type == nil {
+		return "", nil, false
+	}
+	fields, ok := v.nilFields(v.pass.TypesInfo.TypeOf(lit))
+	if !ok {
+		return "", nil, false
+	}
+	set := make(map[string]bool)
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			// every field is set
+			return "", nil, false
+		}
+		if key, ok := kv.Key.(*ast.Ident); ok {
+			set[key.Name] = true
+		}
+	}
+	var unset []*types.Var
+	for _, field := range fields {
+		if !set[field.Name()] {
+			unset = append(unset, field)
+		}
+	}
+	return prefix, unset, true
+}
+
+// nilFields returns the slice fields of a struct type that the package can
+// assign to, reporting false if t is not a struct.
+func (v *returnsVisitor) nilFields(t types.Type) ([]*types.Var, bool) {
+	if t == nil {
+		return nil, false
+	}
+	if _, ok := types.Unalias(t).(*types.TypeParam); ok {
+		return nil, false
+	}
+	s, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, false
+	}
+	var fields []*types.Var
+	for i := range s.NumFields() {
+		field := s.Field(i)
+		if !isSliceType(field.Type()) || field.Name() == "_" || (!field.Exported() && field.Pkg() != v.pass.Pkg) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, true
+}
+
+// typeExprAt builds an expression for t as it can be written in the file
+// containing pos, reporting false if it refers to a package not imported there.
+func (v *returnsVisitor) typeExprAt(t types.Type, pos token.Pos) (ast.Expr, bool) {
+	file := v.file(pos)
+	if file == nil {
+		return nil, false
+	}
+	ok := true
+	text := types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == v.pass.Pkg {
+			return ""
+		}
+		for _, spec := range file.Imports {
+			if specPath, err := strconv.Unquote(spec.Path.Value); err == nil && specPath == pkg.Path() {
+				if spec.Name == nil {
+					return pkg.Name()
+				}
+				if spec.Name.Name != "_" && spec.Name.Name != "." {
+					return spec.Name.Name
+				}
+			}
+		}
+		ok = false
+		return pkg.Name()
+	})
+	if !ok {
+		return nil, false
+	}
+	expr, err := parser.ParseExpr(text)
+	if err != nil {
+		return nil, false
+	}
+	return expr, true
+}
+
+// declareSelection declares the slice assigned to a selection from a variable
+// by the i'th value of an assignment, e.g. `res.Rows = []Row{}` or `*out = nil`.
+func (v *returnsVisitor) declareSelection(s *ast.AssignStmt, i int) {
+	lhs, rhs := s.Lhs[i], s.Rhs[i]
+	root, steps, ok := v.path(lhs)
+	if !ok || steps == "" || !v.isSlice(lhs) {
+		return
+	}
+	name, ok := exprText(ast.Unparen(lhs))
+	if !ok {
+		return
+	}
+
+	sliceDecl := &sliceDeclaration{pos: s.Pos(), stmt: s, index: i}
+	if typeExpr, lenExpr, ok := v.isCreateArray(rhs); ok {
+		sliceDecl.capExpr = lenExpr
+		sliceDecl.typeExpr = typeExpr
+		sliceDecl.fixable = isEmptyLen(lenExpr) && makeCap(rhs) == nil
+		sliceDecl.made = v.madeWith(rhs)
+		sliceDecl.capArg = makeCap(rhs)
+	} else if v.isNil(rhs) {
+		typeExpr, ok := v.typeExprAt(v.pass.TypesInfo.TypeOf(lhs), s.Pos())
+		if !ok {
+			return
+		}
+		sliceDecl.typeExpr = typeExpr
+		sliceDecl.fixable = true
+	} else {
+		return
+	}
+	v.declareAt(root, steps, name, v.pass.TypesInfo.TypeOf(lhs), sliceDecl)
+}
+
+// declareFields declares the nil slice fields of a struct declared by stmt,
+// which are made just before the first loop appending to them.
+func (v *returnsVisitor) declareFields(stmt ast.Stmt, ident *ast.Ident, prefix string, fields []*types.Var) {
+	root := v.pass.TypesInfo.ObjectOf(ident)
+	if root == nil || ident.Name == "_" {
+		return
+	}
+	for _, field := range fields {
+		sliceDecl := &sliceDeclaration{pos: stmt.Pos(), fixable: true, implicit: true}
+		if typeExpr, ok := v.typeExprAt(field.Type(), stmt.Pos()); ok {
+			sliceDecl.typeExpr = typeExpr
+		}
+		v.declareAt(root, prefix+"."+field.Name(), ident.Name+"."+field.Name(), field.Type(), sliceDecl)
+	}
+}
+
+// declareAt declares a slice held by the steps selecting from root.
+func (v *returnsVisitor) declareAt(root types.Object, steps, name string, t types.Type, sliceDecl *sliceDeclaration) {
+	sliceDecl.name = name
+	sliceDecl.obj = v.pathVar(root, steps, t)
+	sliceDecl.root, sliceDecl.steps = root, steps
+	sliceDecl.depth = v.depth
+	sliceDecl.list = v.list
+	v.sliceDeclarations = append(v.sliceDeclarations, sliceDecl)
+}
@@ -3,8 +3,12 @@ package pkg
 import (
 	"bytes"
 	"go/ast"
+	"go/constant"
 	"go/format"
 	"go/token"
+	"go/types"
+	"math"
+	"math/bits"
 	"strconv"
 
 	"golang.org/x/tools/go/analysis"
@@ -12,31 +16,109 @@ import (
 
 type sliceDeclaration struct {
 	name       string
+	obj        types.Object // variable holding the slice, or standing for the selection holding it
+	root       types.Object // variable the slice is selected from, or obj itself
+	steps      string       // selections from root leading to the slice, as described by path
+	depth      int          // conditional nesting depth of the declaration
 	pos        token.Pos
 	eligible   bool
-	ineligible bool
+	ineligible string // reason the slice cannot be preallocated, if any
 	capExpr    ast.Expr
+	upperBound bool // capacity is the most the slice could need
+	// use of the slice preventing preallocation should a later loop append to it
+	touched string
+	// elements appended outside of loops since the last loop appending to the slice
+	pending           ast.Expr
+	pendingUpperBound bool
+	// elements appended by each iteration of a loop when they vary between
+	// iterations, requiring a counting pre-pass to compute the capacity
+	perIteration ast.Expr
+	// fields used to build the suggested fix
+	stmt     ast.Stmt       // statement that declares the slice
+	spec     *ast.ValueSpec // value spec that declares the slice (nil for assignments)
+	index    int            // index of the slice in the spec or assignment
+	typeExpr ast.Expr       // declared slice type
+	fixable  bool           // declaration starts with an empty slice
+	list     []ast.Stmt     // statement list containing the declaration
+	loop     ast.Stmt       // first loop appending to the slice
+	last     ast.Stmt       // last loop appending to the slice
+	implicit bool           // nil field of a struct or named result, made just before the loop
+	entry    ast.Stmt       // first statement of the function, before which a named result is made
+	move     bool           // capacity only compiles at the loop
+	kind     declKind
+	made     *ast.CallExpr // make call declaring the slice, if any
+	capArg   ast.Expr      // capacity passed to make, if any
+	// misuse of the slice's length, reported instead of any preallocation hint
+	bug *analysis.Diagnostic
+	// elements written by index or copy, such that the slice's length is in use
+	filled bool
+	// use after the loop telling a nil slice from an empty one, if any
+	nilUse string
 }
 
+// declKind distinguishes the values that can be preallocated.
+type declKind int
+
+const (
+	sliceKind   declKind = iota
+	mapKind              // preallocated with a size hint
+	builderKind          // strings.Builder or bytes.Buffer, preallocated by growing it before the loop
+	growKind             // slice the function did not make, grown with slices.Grow before the loop
+)
+
 type returnsVisitor struct {
+	pass *analysis.Pass
 	// flags
 	simple            bool
 	includeRangeLoops bool
 	includeForLoops   bool
+	includeMaps       bool
 	// visitor fields
-	sliceDeclarations []*sliceDeclaration
+	sliceDeclarations []*sliceDeclaration // declarations in scope, innermost last
+	depth             int                 // conditional nesting depth of the current statement
+	list              []ast.Stmt          // statement list being analyzed
+	whileInits        map[*ast.ForStmt]ast.Stmt
+	paths             map[pathKey]types.Object // objects standing for selections from variables
+	capped            map[types.Object]bool    // slices last made with a known capacity
+	exported          bool                     // analyzing the body of an exported function
+	benchmark         bool                     // analyzing a benchmark in a test file
+	results           []*sliceDeclaration      // named results of the function being analyzed
 	preallocHints     []analysis.Diagnostic
 }
 
 var invalid = &ast.BadExpr{}
 
-func Check(files []*ast.File, simple, includeRangeLoops, includeForLoops bool) []analysis.Diagnostic {
+// Diagnostic categories, distinguishing hints for slices from those for maps and builders.
+const (
+	CategorySlice   = "slice"
+	CategoryMap     = "map"
+	CategoryBuilder = "builder"
+	// CategoryBug marks likely bugs rather than missed optimizations.
+	CategoryBug = "bug"
+)
+
+// Options selects what Check reports, matching the flags of the analyzer.
+// Options added later default to their zero value, leaving existing callers
+// unaffected.
+type Options struct {
+	Simple     bool // only report loops that do not exit early
+	RangeLoops bool // report range loops
+	ForLoops   bool // report for loops
+	Maps       bool // report size hints for maps
+}
+
+// Check returns the preallocation hints for the files of pass, which must
+// provide type information.
+func Check(pass *analysis.Pass, opts Options) []analysis.Diagnostic {
 	var hints []analysis.Diagnostic
-	for _, f := range files {
+	for _, f := range pass.Files {
 		retVis := &returnsVisitor{
-			simple:            simple,
-			includeRangeLoops: includeRangeLoops,
-			includeForLoops:   includeForLoops,
+			pass:              pass,
+			simple:            opts.Simple,
+			includeRangeLoops: opts.RangeLoops,
+			includeForLoops:   opts.ForLoops,
+			includeMaps:       opts.Maps,
+			whileInits:        collectWhileInits(f),
 		}
 		ast.Walk(retVis, f)
 		hints = append(hints, retVis.preallocHints...)
@@ -46,84 +128,486 @@ func Check(files []*ast.File, simple, includeRangeLoops, includeForLoops bool) [
 }
 
 func (v *returnsVisitor) Visit(node ast.Node) ast.Visitor {
-	v.sliceDeclarations = nil
-
-	blockStmt, ok := node.(*ast.BlockStmt)
-	if !ok {
-		return v
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		v.benchmark = v.isBenchmark(n)
+		if n.Body != nil {
+			v.function(n.Name, n.Type, n.Body)
+		}
+		return nil
+	case *ast.FuncLit:
+		v.benchmark = false
+		v.function(nil, n.Type, n.Body)
+		return nil
 	}
+	return v
+}
 
-	for _, stmt := range blockStmt.List {
-		switch s := stmt.(type) {
-		// Find non pre-allocated slices
-		case *ast.DeclStmt:
-			genD, ok := s.Decl.(*ast.GenDecl)
-			if !ok || genD.Tok != token.VAR {
+// scope analyzes the statements of a function or loop body, whose loops
+// cannot feed slices declared outside of it.
+func (v *returnsVisitor) scope(list []ast.Stmt) {
+	sliceDeclarations, depth := v.sliceDeclarations, v.depth
+	v.sliceDeclarations, v.depth = nil, 0
+	v.stmts(list)
+	v.sliceDeclarations, v.depth = sliceDeclarations, depth
+}
+
+// function analyzes the body of a function, named unless it is a literal,
+// whose named results start out as nil slices that can be made at the start
+// of the body.
+func (v *returnsVisitor) function(name *ast.Ident, typ *ast.FuncType, body *ast.BlockStmt) {
+	sliceDeclarations, depth, exported, results := v.sliceDeclarations, v.depth, v.exported, v.results
+	v.sliceDeclarations, v.depth = nil, 0
+	v.exported, v.results = name != nil && name.IsExported(), nil
+	v.declareParams(typ)
+	if typ.Results != nil {
+		for _, field := range typ.Results.List {
+			if !v.isSlice(field.Type) {
 				continue
 			}
-			for _, spec := range genD.Specs {
-				vSpec, ok := spec.(*ast.ValueSpec)
-				if !ok {
+			for _, name := range field.Names {
+				if name.Name == "_" {
 					continue
 				}
+				sliceDecl := &sliceDeclaration{
+					pos:      name.Pos(),
+					typeExpr: field.Type,
+					fixable:  true,
+					implicit: true,
+				}
+				if len(body.List) > 0 {
+					sliceDecl.entry = body.List[0]
+				}
+				v.declare(name, sliceDecl)
+				v.results = append(v.results, sliceDecl)
+			}
+		}
+	}
+	v.stmts(body.List)
+	v.report(v.sliceDeclarations)
+	v.sliceDeclarations, v.depth = sliceDeclarations, depth
+	v.exported, v.results = exported, results
+}
 
-				if len(vSpec.Values) == 0 {
-					if _, ok := inferExprType(vSpec.Type).(*ast.ArrayType); ok {
-						for _, vName := range vSpec.Names {
-							v.sliceDeclarations = append(v.sliceDeclarations, &sliceDeclaration{name: vName.Name, pos: s.Pos()})
-						}
-					}
-				} else {
+// stmts analyzes a statement list, reporting the slices declared in it once
+// every loop that may append to them has been seen.
+func (v *returnsVisitor) stmts(list []ast.Stmt) {
+	n, outer := len(v.sliceDeclarations), v.list
+	v.list = list
+	for _, stmt := range list {
+		v.stmt(stmt)
+	}
+	v.list = outer
+	v.report(v.sliceDeclarations[n:])
+	v.sliceDeclarations = v.sliceDeclarations[:n]
+}
+
+// conditional analyzes a statement list that may not run, such that loops
+// within it only bound the capacity of slices declared outside of it.
+func (v *returnsVisitor) conditional(list []ast.Stmt) {
+	v.depth++
+	v.stmts(list)
+	v.depth--
+}
+
+func (v *returnsVisitor) stmt(stmt ast.Stmt) {
+	// loops are analyzed along with their label, if any,
+	// so that branches targeting the label are classified correctly
+	loopStmt := stmt
+	if labeled, ok := stmt.(*ast.LabeledStmt); ok {
+		stmt = labeled.Stmt
+	}
+
+	switch s := stmt.(type) {
+	// Find non pre-allocated slices
+	case *ast.DeclStmt:
+		v.inspect(s)
+		genD, ok := s.Decl.(*ast.GenDecl)
+		if !ok || genD.Tok != token.VAR {
+			return
+		}
+		for _, spec := range genD.Specs {
+			vSpec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+
+			if len(vSpec.Values) == 0 {
+				if v.isSlice(vSpec.Type) {
 					for i, vName := range vSpec.Names {
-						if i >= len(vSpec.Values) {
-							break
+						v.declare(vName, &sliceDeclaration{
+							pos:      s.Pos(),
+							stmt:     s,
+							spec:     vSpec,
+							index:    i,
+							typeExpr: vSpec.Type,
+							fixable:  true,
+						})
+					}
+				} else if v.isBuilder(vSpec.Type) {
+					for _, vName := range vSpec.Names {
+						v.declare(vName, &sliceDeclaration{
+							pos:     s.Pos(),
+							stmt:    s,
+							spec:    vSpec,
+							fixable: true,
+							kind:    builderKind,
+						})
+					}
+				} else if fields, ok := v.nilFields(v.pass.TypesInfo.TypeOf(vSpec.Type)); ok {
+					for _, vName := range vSpec.Names {
+						v.declareFields(s, vName, "", fields)
+					}
+				}
+			} else {
+				for i, vName := range vSpec.Names {
+					if i >= len(vSpec.Values) {
+						break
+					}
+					if typeExpr, lenExpr, ok := v.isCreateArray(vSpec.Values[i]); ok {
+						if vSpec.Type != nil {
+							typeExpr = vSpec.Type
 						}
-						if lenExpr, ok := isCreateArray(vSpec.Values[i]); ok {
-							v.sliceDeclarations = append(v.sliceDeclarations, &sliceDeclaration{name: vName.Name, pos: s.Pos(), capExpr: lenExpr})
+						v.declare(vName, &sliceDeclaration{
+							pos:      s.Pos(),
+							capExpr:  lenExpr,
+							stmt:     s,
+							spec:     vSpec,
+							index:    i,
+							typeExpr: typeExpr,
+							fixable:  isEmptyLen(lenExpr) && makeCap(vSpec.Values[i]) == nil,
+							made:     v.madeWith(vSpec.Values[i]),
+							capArg:   makeCap(vSpec.Values[i]),
+						})
+						continue
+					}
+					if v.isCreateBuilder(vSpec.Values[i]) {
+						v.declare(vName, &sliceDeclaration{
+							pos:     s.Pos(),
+							stmt:    s,
+							spec:    vSpec,
+							index:   i,
+							fixable: true,
+							kind:    builderKind,
+						})
+						continue
+					}
+					if typeExpr, lenExpr, ok := v.isCreateMap(vSpec.Values[i]); ok && v.includeMaps {
+						if vSpec.Type != nil {
+							typeExpr = vSpec.Type
 						}
+						v.declare(vName, &sliceDeclaration{
+							pos:      s.Pos(),
+							capExpr:  lenExpr,
+							stmt:     s,
+							spec:     vSpec,
+							index:    i,
+							typeExpr: typeExpr,
+							fixable:  lenExpr == nil,
+							kind:     mapKind,
+						})
+						continue
+					}
+					if prefix, fields, ok := v.isZeroStruct(vSpec.Values[i]); ok {
+						v.declareFields(s, vName, prefix, fields)
+						continue
+					}
+					if v.isGrowable(vSpec.Values[i]) {
+						v.declare(vName, &sliceDeclaration{
+							pos:     s.Pos(),
+							stmt:    s,
+							spec:    vSpec,
+							index:   i,
+							fixable: v.allowsGrow(s.Pos()),
+							kind:    growKind,
+						})
 					}
 				}
 			}
+		}
 
-		case *ast.AssignStmt:
-			for i, lhs := range s.Lhs {
-				if i >= len(s.Rhs) {
-					break
-				}
-				ident, ok := lhs.(*ast.Ident)
-				if !ok {
-					continue
-				}
-				if lenExpr, ok := isCreateArray(s.Rhs[i]); ok {
-					v.sliceDeclarations = append(v.sliceDeclarations, &sliceDeclaration{name: ident.Name, pos: s.Pos(), capExpr: lenExpr})
+	case *ast.AssignStmt:
+		v.inspect(s)
+		v.foldAppends(s)
+		for i, lhs := range s.Lhs {
+			if i >= len(s.Rhs) {
+				break
+			}
+			ident, ok := lhs.(*ast.Ident)
+			if !ok {
+				if s.Tok == token.ASSIGN {
+					v.declareSelection(s, i)
 				}
+				continue
 			}
-
-		case *ast.RangeStmt:
-			if !v.includeRangeLoops || len(v.sliceDeclarations) == 0 {
+			if typeExpr, lenExpr, ok := v.isCreateArray(s.Rhs[i]); ok {
+				v.declare(ident, &sliceDeclaration{
+					pos:      s.Pos(),
+					capExpr:  lenExpr,
+					stmt:     s,
+					index:    i,
+					typeExpr: typeExpr,
+					fixable:  isEmptyLen(lenExpr) && makeCap(s.Rhs[i]) == nil,
+					made:     v.madeWith(s.Rhs[i]),
+					capArg:   makeCap(s.Rhs[i]),
+				})
 				continue
 			}
-			if s.Body != nil {
-				v.handleLoops(s, s.Body)
+			if v.isCreateBuilder(s.Rhs[i]) {
+				v.declare(ident, &sliceDeclaration{
+					pos:     s.Pos(),
+					stmt:    s,
+					index:   i,
+					fixable: true,
+					kind:    builderKind,
+				})
+				continue
 			}
-
-		case *ast.ForStmt:
-			if !v.includeForLoops || len(v.sliceDeclarations) == 0 {
+			if typeExpr, lenExpr, ok := v.isCreateMap(s.Rhs[i]); ok && v.includeMaps {
+				v.declare(ident, &sliceDeclaration{
+					pos:      s.Pos(),
+					capExpr:  lenExpr,
+					stmt:     s,
+					index:    i,
+					typeExpr: typeExpr,
+					fixable:  lenExpr == nil,
+					kind:     mapKind,
+				})
 				continue
 			}
-			if s.Init == nil || s.Cond == nil || s.Post == nil {
+			if prefix, fields, ok := v.isZeroStruct(s.Rhs[i]); ok {
+				v.declareFields(s, ident, prefix, fields)
 				continue
 			}
-			if s.Body != nil {
-				v.handleLoops(s, s.Body)
+			if v.isGrowable(s.Rhs[i]) && ident.Name != "_" && !v.keepsCapacity(ident, s.Rhs[i]) {
+				// e.g., `buf := pool.Get()` or `buf = buf[:0]`
+				v.declare(ident, &sliceDeclaration{
+					pos:     s.Pos(),
+					stmt:    s,
+					index:   i,
+					fixable: v.allowsGrow(s.Pos()),
+					kind:    growKind,
+				})
+			}
+		}
+
+	case *ast.IncDecStmt:
+		v.inspect(s)
+		v.foldInsert(s.X)
+
+	case *ast.ExprStmt:
+		v.inspect(s)
+		v.foldWrite(s.X)
+
+	case *ast.BlockStmt:
+		v.stmts(s.List)
+
+	case *ast.IfStmt:
+		v.inspect(s.Init)
+		v.inspect(s.Cond)
+		v.conditional(s.Body.List)
+		if s.Else != nil {
+			v.conditional([]ast.Stmt{s.Else})
+		}
+
+	case *ast.SwitchStmt:
+		v.inspect(s.Init)
+		v.inspect(s.Tag)
+		v.clauses(s.Body)
+
+	case *ast.TypeSwitchStmt:
+		v.inspect(s.Init)
+		v.inspect(s.Assign)
+		v.clauses(s.Body)
+
+	case *ast.SelectStmt:
+		v.clauses(s.Body)
+
+	case *ast.RangeStmt:
+		if v.trackedSlice(s.X) == nil {
+			v.inspect(s.X)
+		}
+		if len(v.sliceDeclarations) > 0 {
+			if v.includeRangeLoops {
+				v.handleLoops(loopStmt, s.Body)
+			} else {
+				v.skipLoop(s)
+			}
+		}
+		if v.includeRangeLoops {
+			v.groupBy(loopStmt, s)
+		}
+		v.scope(s.Body.List)
+
+	case *ast.ForStmt:
+		v.inspect(s.Init)
+		v.inspect(s.Cond)
+		v.inspect(s.Post)
+		if len(v.sliceDeclarations) > 0 {
+			if v.includeForLoops && s.Cond != nil && ((s.Init != nil && s.Post != nil) || v.whileLoop(s) != nil) {
+				v.handleLoops(loopStmt, s.Body)
+			} else {
+				v.skipLoop(s)
 			}
 		}
+		v.scope(s.Body.List)
+
+	case *ast.ReturnStmt:
+		v.inspect(s)
+		v.foldReturn(s)
+
+	default:
+		v.inspect(s)
+	}
+}
+
+// clauses analyzes the bodies of the case or comm clauses of a switch or select.
+func (v *returnsVisitor) clauses(body *ast.BlockStmt) {
+	for _, clause := range body.List {
+		switch cc := clause.(type) {
+		case *ast.CaseClause:
+			// switch and type switch clause bodies are bare statement lists
+			for _, expr := range cc.List {
+				v.inspect(expr)
+			}
+			v.conditional(cc.Body)
+		case *ast.CommClause:
+			v.inspect(cc.Comm)
+			v.conditional(cc.Body)
+		}
+	}
+}
+
+// inspect analyzes the bodies of any function literals and records the uses
+// of slices within the expressions of a simple statement or the header of a
+// compound one.
+func (v *returnsVisitor) inspect(node ast.Node) {
+	if node == nil {
+		return
+	}
+	v.touches(node)
+	v.nilUses(node)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			v.function(nil, lit.Type, lit.Body)
+			return false
+		}
+		return true
+	})
+}
+
+// declare records a slice declaration, identified by the object it defines or assigns.
+func (v *returnsVisitor) declare(ident *ast.Ident, sliceDecl *sliceDeclaration) {
+	obj := v.pass.TypesInfo.ObjectOf(ident)
+	if obj == nil {
+		return
+	}
+	sliceDecl.name = ident.Name
+	sliceDecl.obj, sliceDecl.root = obj, obj
+	if v.capped == nil {
+		v.capped = make(map[types.Object]bool)
+	}
+	v.capped[obj] = sliceDecl.made != nil && (sliceDecl.capArg != nil || !isEmptyLen(sliceDecl.made.Args[1]))
+	sliceDecl.depth = v.depth
+	sliceDecl.list = v.list
+	v.sliceDeclarations = append(v.sliceDeclarations, sliceDecl)
+}
+
+// findSlice returns the latest declaration of the given slice in scope, if any.
+func (v *returnsVisitor) findSlice(obj types.Object) *sliceDeclaration {
+	for i := len(v.sliceDeclarations) - 1; i >= 0; i-- {
+		if v.sliceDeclarations[i].obj == obj {
+			return v.sliceDeclarations[i]
+		}
+	}
+	return nil
+}
+
+func (v *returnsVisitor) report(sliceDeclarations []*sliceDeclaration) {
+	for _, sliceDecl := range sliceDeclarations {
+		if !sliceDecl.eligible || sliceDecl.ineligible != "" || sliceDecl.perIteration != nil ||
+			sliceDecl.capExpr == nil || sliceDecl.capExpr == invalid {
+			continue
+		}
+
+		// appends since the last loop
+		sliceDecl.capExpr = exprIntAdd(sliceDecl.capExpr, sliceDecl.pending)
+		sliceDecl.upperBound = sliceDecl.upperBound || sliceDecl.pendingUpperBound
+		sliceDecl.pending, sliceDecl.pendingUpperBound = nil, false
+
+		if v.referencedVars(sliceDecl.capExpr)[sliceDecl.root] {
+			// e.g., `for _, v := range x { x = append(x, v) }`, whose
+			// capacity cannot be evaluated before the slice is made
+			sliceDecl.ineligible = "capacity refers to the slice"
+			continue
+		}
+
+		if sliceDecl.kind == builderKind || sliceDecl.kind == growKind {
+			// the builder or slice is grown just before the loop, by an int
+			capExpr, ok := v.capacityAt(sliceDecl.capExpr, sliceDecl.loop.Pos())
+			if ok && !v.changedAfter(sliceDecl, sliceDecl.loop.Pos()) {
+				capExpr, ok = v.intExpr(capExpr, sliceDecl.loop.Pos())
+			} else {
+				ok = false
+			}
+			if !ok {
+				capExpr = invalid
+			}
+			sliceDecl.capExpr = capExpr
+			continue
+		}
+		if sliceDecl.implicit {
+			// a named result is made at the start of the function if
+			// possible, and anything else just before the loop
+			if sliceDecl.entry != nil {
+				if capExpr, ok := v.capacityAt(sliceDecl.capExpr, sliceDecl.entry.Pos()); ok && !v.changedAfter(sliceDecl, sliceDecl.entry.Pos()) {
+					sliceDecl.capExpr = capExpr
+					continue
+				}
+				sliceDecl.entry = nil
+			}
+			if capExpr, ok := v.capacityAt(sliceDecl.capExpr, sliceDecl.loop.Pos()); ok && !v.changedAfter(sliceDecl, sliceDecl.loop.Pos()) {
+				sliceDecl.capExpr = capExpr
+			} else {
+				sliceDecl.capExpr = invalid
+			}
+			continue
+		}
+		// the capacity must compile where the slice is made
+		if capExpr, ok := v.capacityAt(sliceDecl.capExpr, sliceDecl.pos); ok && !v.changedAfter(sliceDecl, sliceDecl.pos) {
+			sliceDecl.capExpr = capExpr
+		} else if capExpr, ok := v.capacityAt(sliceDecl.capExpr, sliceDecl.loop.Pos()); ok && sliceDecl.steps == "" &&
+			!v.changedAfter(sliceDecl, sliceDecl.loop.Pos()) {
+			sliceDecl.capExpr, sliceDecl.move = capExpr, true
+		} else {
+			sliceDecl.capExpr = invalid
+		}
 	}
 
 	buf := bytes.NewBuffer(nil)
 
-	for _, sliceDecl := range v.sliceDeclarations {
-		if !sliceDecl.eligible || sliceDecl.ineligible {
+	for _, sliceDecl := range sliceDeclarations {
+		if sliceDecl.bug != nil {
+			v.preallocHints = append(v.preallocHints, *sliceDecl.bug)
+			continue
+		}
+		if !sliceDecl.eligible || sliceDecl.ineligible != "" {
+			continue
+		}
+		if sliceDecl.capArg != nil {
+			// already preallocated, but possibly not enough
+			v.checkCapacity(sliceDecl)
+			continue
+		}
+
+		appends, elements, capacity, category := " appends ", " elements", " with capacity ", CategorySlice
+		switch sliceDecl.kind {
+		case mapKind:
+			appends, capacity, category = " inserts ", " with size hint ", CategoryMap
+		case builderKind:
+			appends, elements, category = " writes ", " bytes", CategoryBuilder
+		case growKind:
+			v.reportGrow(sliceDecl)
 			continue
 		}
 
@@ -131,225 +615,292 @@ func (v *returnsVisitor) Visit(node ast.Node) ast.Visitor {
 		buf.WriteString("Consider preallocating ")
 		buf.WriteString(sliceDecl.name)
 
-		if sliceDecl.capExpr != nil && sliceDecl.capExpr != invalid {
+		hasCap, keepsNil := false, false
+		if sliceDecl.perIteration != nil {
+			undo := buf.Len()
+			buf.WriteString(" using a counting pre-pass, as each iteration")
+			buf.WriteString(appends)
+			if format.Node(buf, token.NewFileSet(), sliceDecl.perIteration) != nil {
+				buf.Truncate(undo)
+			} else {
+				buf.WriteString(elements)
+			}
+		} else if sliceDecl.capExpr != nil && sliceDecl.capExpr != invalid {
 			undo := buf.Len()
-			buf.WriteString(" with capacity ")
+			buf.WriteString(capacity)
+			if sliceDecl.upperBound {
+				buf.WriteString("at most ")
+			}
 			if format.Node(buf, token.NewFileSet(), sliceDecl.capExpr) != nil {
 				buf.Truncate(undo)
+			} else {
+				hasCap, keepsNil = true, v.keepsNil(sliceDecl)
+				if sliceDecl.move && (keepsNil && !sliceDecl.upperBound || !v.canMove(sliceDecl)) {
+					// the declaration stays, to keep the slice nil or as it
+					// cannot be moved
+					buf.WriteString(" by making it before the loop")
+				} else if sliceDecl.move {
+					buf.WriteString(" by moving its declaration down to the loop")
+				} else if sliceDecl.kind == builderKind {
+					buf.WriteString(" by growing it before the loop")
+				} else if sliceDecl.implicit && sliceDecl.entry == nil {
+					buf.WriteString(" by making it before the loop")
+				}
+				if keepsNil && sliceDecl.upperBound {
+					buf.WriteString(", although it is " + sliceDecl.nilUse + " and is no longer nil if empty")
+				} else if keepsNil {
+					buf.WriteString(" only if non-empty, as it is " + sliceDecl.nilUse)
+				}
+			}
+		}
+
+		var fixes []analysis.SuggestedFix
+		if keepsNil {
+			// only a slice with an exact capacity is made if non-empty,
+			// which is the case if and only if the loop appends to it
+			if !sliceDecl.upperBound && sliceDecl.fixable {
+				if fix, ok := v.suggestNonEmpty(sliceDecl); ok {
+					fixes = append(fixes, fix)
+				}
+			}
+		} else if hasCap && sliceDecl.fixable {
+			if sliceDecl.kind == builderKind {
+				if fix, ok := v.suggestGrow(sliceDecl); ok {
+					fixes = append(fixes, fix)
+				}
+			} else if sliceDecl.implicit {
+				if fix, ok := v.suggestField(sliceDecl); ok {
+					fixes = append(fixes, fix)
+				}
+			} else if sliceDecl.move {
+				if v.canMove(sliceDecl) {
+					if fix, ok := v.suggestMove(sliceDecl); ok {
+						fixes = append(fixes, fix)
+					}
+				} else if sliceDecl.fixable && !v.assignedBefore(sliceDecl) {
+					if fix, ok := v.suggestField(sliceDecl); ok {
+						fixes = append(fixes, fix)
+					}
+				}
+			} else if fix, ok := v.suggestMake(sliceDecl); ok {
+				fixes = append(fixes, fix)
 			}
 		}
 
 		v.preallocHints = append(v.preallocHints, analysis.Diagnostic{
-			Pos:     sliceDecl.pos,
-			Message: buf.String(),
+			Pos:            sliceDecl.pos,
+			Category:       category,
+			Message:        buf.String(),
+			SuggestedFixes: fixes,
 		})
 	}
-
-	return v
 }
 
-func isCreateArray(expr ast.Expr) (ast.Expr, bool) {
+// isCreateArray reports whether expr creates a new slice, returning the
+// type of the slice and its initial length (nil when empty).
+func (v *returnsVisitor) isCreateArray(expr ast.Expr) (ast.Expr, ast.Expr, bool) {
 	switch e := expr.(type) {
 	case *ast.CompositeLit:
 		// []any{...}
-		_, ok := inferExprType(e.Type).(*ast.ArrayType)
-		if ok && len(e.Elts) > 0 {
-			return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(e.Elts))}, true
+		if e.Type == nil || !v.isSlice(e) {
+			return nil, nil, false
+		}
+		if len(e.Elts) > 0 {
+			return e.Type, &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(e.Elts))}, true
 		}
-		return nil, ok
+		return e.Type, nil, true
 	case *ast.CallExpr:
 		switch len(e.Args) {
 		case 1:
 			// []any(nil)
-			arg, ok := e.Args[0].(*ast.Ident)
-			if !ok || arg.Name != "nil" {
-				return nil, false
+			if !v.isNil(e.Args[0]) || !v.pass.TypesInfo.Types[e.Fun].IsType() || !v.isSlice(e.Fun) {
+				return nil, nil, false
 			}
-			_, ok = inferExprType(e.Fun).(*ast.ArrayType)
-			return nil, ok
-		case 2:
-			// make([]any, n)
-			ident, ok := e.Fun.(*ast.Ident)
-			if !ok || ident.Name != "make" {
-				return nil, false
+			return e.Fun, nil, true
+		case 2, 3:
+			// make([]any, n) or make([]any, n, c)
+			if !v.isBuiltin(e.Fun, "make") || !v.isSlice(e.Args[0]) {
+				return nil, nil, false
 			}
-			return e.Args[1], true
+			return e.Args[0], e.Args[1], true
 		}
 	}
-	return nil, false
+	return nil, nil, false
 }
 
-// handleLoops is a helper function to share the logic required for both *ast.RangeLoops and *ast.ForLoops
-func (v *returnsVisitor) handleLoops(loopStmt ast.Stmt, blockStmt *ast.BlockStmt) {
-	appendCounters := make(map[string]int, len(v.sliceDeclarations))
-	var hasReturnOrBranch bool
-
-	for _, stmt := range blockStmt.List {
-		switch bodyStmt := stmt.(type) {
-		case *ast.AssignStmt:
-			asgnStmt := bodyStmt
-			for i, lhs := range asgnStmt.Lhs {
-				if i >= len(asgnStmt.Rhs) {
-					break
-				}
-
-				lhsIdent, ok := lhs.(*ast.Ident)
-				if !ok {
-					continue
-				}
-
-				if count, ok := appendCounters[lhsIdent.Name]; ok && count == 0 {
-					// already ineligible due to unsupported append pattern
-					continue
-				}
-
-				callExpr, ok := asgnStmt.Rhs[i].(*ast.CallExpr)
-				if !ok {
-					continue
-				}
-
-				rhsFuncIdent, ok := callExpr.Fun.(*ast.Ident)
-				if !ok || rhsFuncIdent.Name != "append" {
-					continue
-				}
-
-				// e.g., `x = append(x)`
-				// Pointless, but pre-allocation will not help.
-				if len(callExpr.Args) < 2 {
-					continue
-				}
+// isEmptyLen reports whether the initial length returned by isCreateArray is zero.
+func isEmptyLen(lenExpr ast.Expr) bool {
+	if lenExpr == nil {
+		return true
+	}
+	n, ok := exprIntValue(lenExpr)
+	return ok && n == 0
+}
 
-				rhsIdent, ok := callExpr.Args[0].(*ast.Ident)
-				if !ok {
-					continue
-				}
+// unlabel returns the statement labeled by stmt, if any.
+func unlabel(stmt ast.Stmt) ast.Stmt {
+	if labeled, ok := stmt.(*ast.LabeledStmt); ok {
+		return labeled.Stmt
+	}
+	return stmt
+}
 
-				// e.g., `x = append(y, a)`
-				// This is weird (and maybe a logic error),
-				// but we cannot recommend pre-allocation.
-				if lhsIdent.Name != rhsIdent.Name {
-					appendCounters[lhsIdent.Name] = 0
-					continue
-				}
+// handleLoops is a helper function to share the logic required for both *ast.RangeLoops and *ast.ForLoops
+func (v *returnsVisitor) handleLoops(loopStmt ast.Stmt, blockStmt *ast.BlockStmt) {
+	indexed := v.indexedFirst(blockStmt)
+	v.touches(blockStmt)
+	v.nilUses(blockStmt)
+	counter, appendCounters := v.countAppends(blockStmt, v.distinctKeys(loopStmt))
 
-				// e.g., `x = append(x, y...)`
-				// we should ignore this. Pre-allocating in this case
-				// is confusing and is not possible in general.
-				if callExpr.Ellipsis.IsValid() {
-					appendCounters[lhsIdent.Name] = 0
-					continue
-				}
+	exits, _ := v.loopExits(loopStmt)
 
-				appendCounters[lhsIdent.Name] += len(callExpr.Args) - 1
-			}
-		case *ast.IfStmt:
-			ifStmt := bodyStmt
-			if ifStmt.Body == nil {
-				continue
-			}
-			for _, ifBodyStmt := range ifStmt.Body.List {
-				// TODO: should probably handle embedded ifs here
-				switch ifBodyStmt.(type) {
-				case *ast.BranchStmt, *ast.ReturnStmt:
-					hasReturnOrBranch = true
-				}
-			}
-		}
-	}
+	v.markUnsupported(counter.unsupported)
 
-	if len(appendCounters) == 0 {
+	if len(appendCounters) == 0 && len(indexed) == 0 {
 		return
 	}
 
 	var countExpr ast.Expr
-	switch s := loopStmt.(type) {
+	exact := true
+	switch s := unlabel(loopStmt).(type) {
 	case *ast.RangeStmt:
-		countExpr = rangeLoopCount(s)
+		countExpr = v.rangeLoopCount(s)
 	case *ast.ForStmt:
-		countExpr = forLoopCount(s)
+		countExpr, exact = v.forLoopCount(s)
 	}
+	mutated := v.loopCountMutated(loopStmt)
 
 	if count, ok := exprIntValue(countExpr); ok {
 		if count <= 0 {
 			// loop will definitely never iterate (probably a logic error)
 			return
 		}
-		if _, ok := countExpr.(*ast.BasicLit); !ok {
+		if count > v.maxInt() {
+			// capacity would overflow int on the target platform
+			countExpr = invalid
+		} else if _, ok := countExpr.(*ast.BasicLit); !ok {
 			countExpr = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(count)}
 		}
 	}
 
-	for name, appendCount := range appendCounters {
-		for _, sliceDecl := range v.sliceDeclarations {
-			if sliceDecl.name != name {
+	v.indexedEmpty(indexed, loopStmt, countExpr)
+
+	for obj, bounds := range appendCounters {
+		sliceDecl := v.findSlice(obj)
+		if sliceDecl == nil || sliceDecl.ineligible != "" {
+			continue
+		}
+
+		if sliceDecl.touched != "" {
+			sliceDecl.ineligible = sliceDecl.touched
+			continue
+		}
+
+		if mutated != "" {
+			sliceDecl.ineligible = mutated
+			continue
+		}
+
+		if countExpr == invalid {
+			sliceDecl.ineligible = "indeterminate loop count"
+			continue
+		}
+
+		if sliceDecl.made != nil && !isEmptyLen(sliceDecl.made.Args[1]) && sliceDecl.loop == nil && sliceDecl.pending == nil &&
+			!sliceDecl.filled && sameLength(countExpr, sliceDecl.made.Args[1]) {
+			// e.g., `x := make([]T, len(a))` followed by `x = append(x, v)`
+			switch {
+			case v.benchmark:
+				// e.g., measuring appends to a slice that is already full
+				sliceDecl.ineligible = "made with a length in a benchmark"
+				continue
+			case v.pass.TypesInfo.Types[sliceDecl.made.Args[1]].Value == nil:
+				sliceDecl.loop = loopStmt
+				sliceDecl.bug = v.zeroFilled(sliceDecl, bounds, exits)
 				continue
 			}
+			// a constant length, e.g. `make([]byte, 8)`, may be a deliberate
+			// prefix, so the slice is only checked for its capacity
+		}
 
-			if sliceDecl.ineligible {
-				break
-			}
+		if v.simple && exits {
+			sliceDecl.ineligible = "loop may exit early in simple mode"
+			continue
+		}
 
-			if countExpr == invalid {
-				// ineligible due to indeterminate loop count
-				sliceDecl.ineligible = true
-				break
-			}
+		// appends since the previous loop, if any, as a slice the function
+		// did not make is only grown just before the first loop
+		if sliceDecl.kind == growKind && sliceDecl.loop == nil {
+			sliceDecl.pending = nil
+		}
+		sliceDecl.capExpr = exprIntAdd(sliceDecl.capExpr, sliceDecl.pending)
+		sliceDecl.upperBound = sliceDecl.upperBound || sliceDecl.pendingUpperBound
+		sliceDecl.pending, sliceDecl.pendingUpperBound = nil, false
 
-			if v.simple && hasReturnOrBranch {
-				// ineligible due to return/break whilst in simple mode
-				sliceDecl.ineligible = true
-				break
-			}
+		sliceDecl.eligible = true
+		if sliceDecl.loop == nil {
+			sliceDecl.loop = loopStmt
+		}
+		sliceDecl.last = loopStmt
+		if !bounds.min.equal(bounds.max) || exits || !exact || v.depth > sliceDecl.depth {
+			// some iterations append fewer elements, the loop count is a bound,
+			// or the loop may not run at all
+			sliceDecl.upperBound = true
+		}
 
-			if appendCount == 0 {
-				// ineligible due to unsupported append pattern
-				sliceDecl.ineligible = true
-				break
-			}
+		if countExpr == nil {
+			sliceDecl.capExpr = invalid
+			continue
+		}
 
-			sliceDecl.eligible = true
+		total := v.loopTotal(bounds.max, loopStmt, countExpr)
+		if total.perIteration != nil {
+			// capacity varies with the elements iterated over
+			sliceDecl.perIteration = total.perIteration
+			continue
+		}
+		// combine like terms with earlier loops, e.g., `2 * len(a)`
+		if sliceDecl.capExpr != nil {
+			prev, _ := linearCount(sliceDecl.capExpr)
+			total = prev.add(total)
+		}
+		sliceDecl.capExpr = total.expr()
+	}
+}
 
-			if countExpr == nil {
-				sliceDecl.capExpr = invalid
-				break
-			}
+// skipLoop marks the slices appended to within a loop that cannot be analyzed as ineligible.
+func (v *returnsVisitor) skipLoop(loopStmt ast.Stmt) {
+	v.touches(unlabel(loopStmt))
+	v.nilUses(unlabel(loopStmt))
+	c := &appendCounter{v: v, unsupported: make(map[types.Object]bool)}
+	c.unsupportedLoop(loopStmt)
+	v.markUnsupported(c.unsupported)
+}
 
-			capExpr := countExpr
-			if appendCount > 1 {
-				if capInt, ok := exprIntValue(capExpr); ok {
-					capExpr = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(appendCount * capInt)}
-				} else {
-					capExpr = &ast.BinaryExpr{
-						X:  &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(appendCount)},
-						Op: token.MUL,
-						Y:  capExpr,
-					}
-				}
-			}
-			sliceDecl.capExpr = exprIntAdd(sliceDecl.capExpr, capExpr)
+func (v *returnsVisitor) markUnsupported(unsupported map[types.Object]bool) {
+	for obj := range unsupported {
+		if sliceDecl := v.findSlice(obj); sliceDecl != nil && sliceDecl.ineligible == "" {
+			sliceDecl.ineligible = "unsupported append pattern"
 		}
 	}
 }
 
-func rangeLoopCount(stmt *ast.RangeStmt) ast.Expr {
-	switch xType := inferExprType(stmt.X).(type) {
-	case *ast.ChanType, *ast.FuncType:
+func (v *returnsVisitor) rangeLoopCount(stmt *ast.RangeStmt) ast.Expr {
+	switch xType := coreType(v.pass.TypesInfo.TypeOf(stmt.X)).(type) {
+	case *types.Chan, *types.Signature:
 		return invalid
-	case *ast.ArrayType, *ast.MapType:
-	case *ast.StarExpr:
-		if _, ok := xType.X.(*ast.ArrayType); !ok {
+	case *types.Slice, *types.Array, *types.Map:
+	case *types.Pointer:
+		if _, ok := coreType(xType.Elem()).(*types.Array); !ok {
 			return nil
 		}
-	case *ast.Ident:
-		switch xType.Name {
-		case "byte", "rune", "int", "int8", "int16", "int32", "int64",
-			"uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+	case *types.Basic:
+		switch {
+		case xType.Info()&types.IsInteger != 0:
 			return stmt.X
-		case "string":
-			if lit, ok := stmt.X.(*ast.BasicLit); ok && lit.Kind == token.STRING {
-				if str, err := strconv.Unquote(lit.Value); err == nil {
-					return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(str))}
-				}
+		case xType.Info()&types.IsString != 0:
+			if value := v.pass.TypesInfo.Types[stmt.X].Value; value != nil && value.Kind() == constant.String {
+				return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(constant.StringVal(value)))}
 			}
 		default:
 			return nil
@@ -361,20 +912,104 @@ func rangeLoopCount(stmt *ast.RangeStmt) ast.Expr {
 	return &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{stmt.X}}
 }
 
-func forLoopCount(stmt *ast.ForStmt) ast.Expr {
-	initStmt, ok := stmt.Init.(*ast.AssignStmt)
-	if !ok {
-		return nil
+// forLoopStep describes how the post statement of a for loop advances its variable.
+type forLoopStep struct {
+	ident      *ast.Ident // loop variable
+	name       string
+	step       ast.Expr // amount added or subtracted, or bits shifted by
+	factor     int      // amount multiplied or divided by (zero when additive)
+	increasing bool
+}
+
+func parseForLoopStep(post ast.Stmt) (forLoopStep, bool) {
+	one := &ast.BasicLit{Kind: token.INT, Value: "1"}
+
+	switch s := post.(type) {
+	case *ast.IncDecStmt:
+		ident, ok := s.X.(*ast.Ident)
+		if !ok {
+			return forLoopStep{}, false
+		}
+		return forLoopStep{ident: ident, name: ident.Name, step: one, increasing: s.Tok == token.INC}, true
+
+	case *ast.AssignStmt:
+		if len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+			return forLoopStep{}, false
+		}
+		ident, ok := s.Lhs[0].(*ast.Ident)
+		if !ok {
+			return forLoopStep{}, false
+		}
+		step := s.Rhs[0]
+		n, isConst := exprIntValue(step)
+
+		switch s.Tok {
+		case token.ADD_ASSIGN, token.SUB_ASSIGN:
+			// e.g., `i += 2`
+			increasing := s.Tok == token.ADD_ASSIGN
+			if isConst {
+				if n == 0 {
+					return forLoopStep{}, false
+				}
+				if n < 0 {
+					// e.g., `i += -2`
+					increasing = !increasing
+					n = -n
+				}
+				step = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(n)}
+			}
+			return forLoopStep{ident: ident, name: ident.Name, step: step, increasing: increasing}, true
+
+		case token.MUL_ASSIGN, token.QUO_ASSIGN:
+			// e.g., `i *= 2`
+			if !isConst || n < 2 {
+				return forLoopStep{}, false
+			}
+			shift := bits.Len(uint(n)) - 1
+			return forLoopStep{
+				ident:      ident,
+				name:       ident.Name,
+				step:       &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(shift)},
+				factor:     n,
+				increasing: s.Tok == token.MUL_ASSIGN,
+			}, true
+
+		case token.SHL_ASSIGN, token.SHR_ASSIGN:
+			// e.g., `i <<= 1`
+			if !isConst || n < 1 || n >= bits.UintSize-2 {
+				return forLoopStep{}, false
+			}
+			return forLoopStep{
+				ident:      ident,
+				name:       ident.Name,
+				step:       step,
+				factor:     1 << n,
+				increasing: s.Tok == token.SHL_ASSIGN,
+			}, true
+		}
+	}
+
+	return forLoopStep{}, false
+}
+
+// forLoopCount returns the number of iterations of a for loop, and whether
+// that count is exact rather than an upper bound.
+func (v *returnsVisitor) forLoopCount(stmt *ast.ForStmt) (ast.Expr, bool) {
+	if stmt.Init == nil && stmt.Post == nil {
+		// e.g., `i := 0; for i < n { ...; i++ }`
+		if stmt = v.whileLoop(stmt); stmt == nil {
+			return nil, true
+		}
 	}
 
-	postStmt, ok := stmt.Post.(*ast.IncDecStmt)
+	initStmt, ok := stmt.Init.(*ast.AssignStmt)
 	if !ok {
-		return nil
+		return nil, true
 	}
 
-	postIdent, ok := postStmt.X.(*ast.Ident)
+	step, ok := parseForLoopStep(stmt.Post)
 	if !ok {
-		return nil
+		return nil, true
 	}
 
 	index := -1
@@ -382,29 +1017,41 @@ func forLoopCount(stmt *ast.ForStmt) ast.Expr {
 		if i >= len(initStmt.Rhs) {
 			break
 		}
-		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == postIdent.Name {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == step.name {
 			index = i
 			break
 		}
 	}
 	if index < 0 {
-		return nil
+		return nil, true
 	}
 
 	lower := initStmt.Rhs[index]
-	upper, op := forLoopUpperBound(stmt.Cond, postIdent.Name)
+	upper, op := forLoopUpperBound(stmt.Cond, step.name)
+	if upper == nil {
+		return nil, true
+	}
 
-	if postStmt.Tok == token.INC {
+	if step.increasing {
 		if op == token.GTR || op == token.GEQ {
-			return invalid
+			return invalid, true
 		}
 	} else {
 		if op == token.LSS || op == token.LEQ {
-			return invalid
+			return invalid, true
 		}
 		lower, upper = upper, lower
 	}
 
+	if step.factor != 0 {
+		return multiplicativeLoopCount(lower, upper, op, step)
+	}
+
+	if op == token.NEQ && !isOne(step.step) {
+		// the variable may step over the bound
+		return nil, true
+	}
+
 	// negate the lower bound before adding
 	if unary, ok := lower.(*ast.UnaryExpr); ok && unary.Op == token.SUB {
 		lower = unary.X
@@ -416,7 +1063,114 @@ func forLoopCount(stmt *ast.ForStmt) ast.Expr {
 	if op == token.LEQ || op == token.GEQ {
 		countExpr = exprIntAdd(countExpr, &ast.BasicLit{Kind: token.INT, Value: "1"})
 	}
-	return countExpr
+	if subtracts(countExpr) {
+		// the loop runs no times if the variable starts past the bound,
+		// e.g., `max(0, n - m)`
+		if !v.builtinAt("max", stmt.Pos()) {
+			return nil, true
+		}
+		countExpr = &ast.CallExpr{
+			Fun:  ast.NewIdent("max"),
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: "0"}, countExpr},
+		}
+	}
+	if isOne(step.step) {
+		return countExpr, true
+	}
+
+	// ceiling division
+	span, spanOK := exprIntValue(countExpr)
+	n, stepOK := exprIntValue(step.step)
+	if spanOK && stepOK {
+		return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(max(span+n-1, 0) / n)}, true
+	}
+	divisor := step.step
+	if !stepOK {
+		// a step that does not move the variable towards the bound never
+		// terminates the loop, but must not divide by zero should the loop
+		// not run at all, e.g., `max(chunk, 1)`
+		if !v.builtinAt("max", stmt.Pos()) {
+			return nil, true
+		}
+		divisor = &ast.CallExpr{
+			Fun:  ast.NewIdent("max"),
+			Args: []ast.Expr{step.step, &ast.BasicLit{Kind: token.INT, Value: "1"}},
+		}
+	}
+	countExpr = exprIntAdd(exprIntAdd(countExpr, divisor), &ast.BasicLit{Kind: token.INT, Value: "-1"})
+	return &ast.BinaryExpr{X: countExpr, Op: token.QUO, Y: divisor}, true
+}
+
+// subtracts reports whether a non-constant sum subtracts any of its terms, such
+// that it may be negative.
+func subtracts(expr ast.Expr) bool {
+	if _, ok := exprIntValue(expr); ok {
+		return false
+	}
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.UnaryExpr:
+		return e.Op == token.SUB
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.SUB:
+			return true
+		case token.ADD:
+			return subtracts(e.X) || subtracts(e.Y)
+		}
+	}
+	return false
+}
+
+// multiplicativeLoopCount returns the number of iterations of a for loop that
+// multiplies or divides its variable, which is exact when both bounds are
+// constant and otherwise bounded by the number of bits in the larger bound.
+func multiplicativeLoopCount(lower, upper ast.Expr, op token.Token, step forLoopStep) (ast.Expr, bool) {
+	if op == token.NEQ {
+		return nil, true
+	}
+	inclusive := op == token.LEQ || op == token.GEQ
+
+	lo, loOK := exprIntValue(lower)
+	hi, hiOK := exprIntValue(upper)
+	switch {
+	case step.increasing && (!loOK || lo < 1):
+		// zero never grows
+		return nil, true
+	case !step.increasing && (!loOK || lo < 0 || (lo == 0 && inclusive)):
+		// division never goes below zero
+		return nil, true
+	}
+
+	if loOK && hiOK {
+		count := 0
+		for lo < hi || (inclusive && lo == hi) {
+			count++
+			if step.increasing {
+				if lo > math.MaxInt/step.factor {
+					return invalid, true
+				}
+				lo *= step.factor
+			} else {
+				hi /= step.factor
+			}
+		}
+		return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(count)}, true
+	}
+
+	countExpr := bitsLen(upper)
+	if !isOne(step.step) {
+		countExpr = &ast.BinaryExpr{
+			X:  exprIntAdd(exprIntAdd(countExpr, step.step), &ast.BasicLit{Kind: token.INT, Value: "-1"}),
+			Op: token.QUO,
+			Y:  step.step,
+		}
+	}
+	return countExpr, false
+}
+
+func isOne(expr ast.Expr) bool {
+	n, ok := exprIntValue(expr)
+	return ok && n == 1
 }
 
 func forLoopUpperBound(expr ast.Expr, name string) (ast.Expr, token.Token) {
@@ -438,14 +1192,16 @@ func forLoopUpperBound(expr ast.Expr, name string) (ast.Expr, token.Token) {
 					}
 					if call, ok := xExpr.(*ast.CallExpr); ok {
 						if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == funName {
-							call.Args = append(call.Args, yExpr)
-							return xExpr, xOp
+							// a copy, leaving the condition itself unchanged
+							args := append(append([]ast.Expr(nil), call.Args...), yExpr)
+							return &ast.CallExpr{Fun: call.Fun, Args: args}, xOp
 						}
 					}
 					if call, ok := yExpr.(*ast.CallExpr); ok {
 						if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == funName {
-							call.Args = append(call.Args, xExpr)
-							return yExpr, yOp
+							// a copy, leaving the condition itself unchanged
+							args := append(append([]ast.Expr(nil), call.Args...), xExpr)
+							return &ast.CallExpr{Fun: call.Fun, Args: args}, yOp
 						}
 					}
 					return &ast.CallExpr{Fun: ast.NewIdent(funName), Args: []ast.Expr{xExpr, yExpr}}, xOp
@@ -505,6 +1261,19 @@ func exprIntAdd(x, y ast.Expr) ast.Expr {
 		if yInt == 0 {
 			return x
 		}
+		if bin, ok := x.(*ast.BinaryExpr); ok && (bin.Op == token.ADD || bin.Op == token.SUB) {
+			// fold into a trailing constant, e.g., `n + 1 + 2`
+			if c, ok := exprIntValue(bin.Y); ok {
+				if bin.Op == token.SUB {
+					c = -c
+				}
+				return exprIntAdd(bin.X, &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(c + yInt)})
+			}
+			// fold into a leading constant, e.g., `1 + n + 1`
+			if c, ok := exprIntValue(bin.X); ok && bin.Op == token.ADD && c+yInt > 0 {
+				return &ast.BinaryExpr{X: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(c + yInt)}, Op: token.ADD, Y: bin.Y}
+			}
+		}
 		if yInt < 0 {
 			return &ast.BinaryExpr{X: x, Op: token.SUB, Y: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(-yInt)}}
 		}
@@ -3,363 +3,117 @@ package pkg
 import (
 	"go/ast"
 	"go/token"
+	"go/types"
+	"go/version"
 )
 
-func inferExprType(expr ast.Expr) ast.Expr {
-	switch e := expr.(type) {
-	case *ast.ArrayType, *ast.StructType, *ast.FuncType, *ast.InterfaceType, *ast.MapType, *ast.ChanType:
-		return e
-	case *ast.ParenExpr:
-		return inferExprType(e.X)
-	case *ast.SliceExpr:
-		return inferExprType(e.X)
-	case *ast.TypeAssertExpr:
-		return inferExprType(e.Type)
-	case *ast.CompositeLit:
-		return inferExprType(e.Type)
-	case *ast.Ellipsis:
-		return &ast.ArrayType{Elt: e.Elt}
-	case *ast.FuncLit:
-		return &ast.FuncType{Results: e.Type.Results}
-	case *ast.BasicLit:
-		return inferBasicType(e)
-	case *ast.BinaryExpr:
-		return inferBinaryType(e)
-	case *ast.StarExpr:
-		return inferStarType(e)
-	case *ast.UnaryExpr:
-		return inferUnaryType(e)
-	case *ast.CallExpr:
-		return inferCallType(e)
-	case *ast.IndexExpr:
-		return inferIndexType(e)
-	case *ast.IndexListExpr:
-		return inferIndexListType(e)
-	case *ast.SelectorExpr:
-		return inferSelectorType(e)
-	case *ast.Ident:
-		return inferIdentType(e)
-	default:
+// coreType returns the underlying type of t, or for a type parameter the
+// single underlying type shared by every type in its type set (nil if none).
+func coreType(t types.Type) types.Type {
+	if t == nil {
 		return nil
 	}
-}
-
-func inferBasicType(basic *ast.BasicLit) ast.Expr {
-	switch basic.Kind {
-	case token.INT:
-		return ast.NewIdent("int")
-	case token.FLOAT:
-		return ast.NewIdent("float64")
-	case token.IMAG:
-		return ast.NewIdent("imag")
-	case token.CHAR:
-		return ast.NewIdent("char")
-	case token.STRING:
-		return ast.NewIdent("string")
-	default:
-		return nil
-	}
-}
-
-func inferBinaryType(binary *ast.BinaryExpr) ast.Expr {
-	switch binary.Op {
-	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
-		return ast.NewIdent("bool")
-	default:
-		if xType := inferExprType(binary.X); xType != nil {
-			return xType
-		}
-		return inferExprType(binary.Y)
+	if tp, ok := types.Unalias(t).(*types.TypeParam); ok {
+		return constraintCoreType(tp.Constraint())
 	}
+	return t.Underlying()
 }
 
-func inferStarType(star *ast.StarExpr) ast.Expr {
-	switch xType := inferExprType(star.X).(type) {
-	case nil:
-		return nil
-	case *ast.StarExpr:
-		return inferExprType(xType.X)
-	default:
-		return &ast.StarExpr{X: xType}
-	}
-}
-
-func inferUnaryType(unary *ast.UnaryExpr) ast.Expr {
-	if xType := inferExprType(unary.X); xType != nil {
-		switch unary.Op {
-		case token.AND:
-			return &ast.StarExpr{X: xType}
-		case token.ARROW:
-			if ct, ok := xType.(*ast.ChanType); ok {
-				return inferExprType(ct.Value)
+func constraintCoreType(constraint types.Type) types.Type {
+	iface, ok := constraint.Underlying().(*types.Interface)
+	if !ok {
+		return constraint.Underlying()
+	}
+
+	var core types.Type
+	for i := range iface.NumEmbeddeds() {
+		var embeddedCore types.Type
+		switch e := iface.EmbeddedType(i).(type) {
+		case *types.Union:
+			for j := range e.Len() {
+				termCore := e.Term(j).Type().Underlying()
+				if embeddedCore == nil {
+					embeddedCore = termCore
+				} else if !types.Identical(embeddedCore, termCore) {
+					return nil
+				}
 			}
-			return xType
 		default:
-			return xType
+			embeddedCore = constraintCoreType(e)
 		}
-	}
-	return nil
-}
-
-func inferCallType(call *ast.CallExpr) ast.Expr {
-	if id, ok := call.Fun.(*ast.Ident); ok && id.Obj == nil {
-		switch id.Name {
-		case "len", "cap", "copy":
-			return ast.NewIdent("int")
-		case "real", "imag":
-			return ast.NewIdent("float64")
-		case "complex":
-			return ast.NewIdent("complex64")
-		case "recover":
-			return ast.NewIdent("any")
-		case "make", "min", "max":
-			if len(call.Args) > 0 {
-				return inferExprType(call.Args[0])
-			}
-		case "new":
-			if len(call.Args) > 0 {
-				if argType := inferExprType(call.Args[0]); argType != nil {
-					return &ast.StarExpr{X: argType}
-				}
-			}
-		case "append":
-			if len(call.Args) > 0 {
-				if argType := inferExprType(call.Args[0]); argType != nil {
-					return argType
-				}
-				return &ast.ArrayType{}
-			}
+		if embeddedCore == nil {
+			return nil
 		}
-	}
-
-	funType := inferExprType(call.Fun)
-	if ft, ok := funType.(*ast.FuncType); ok && len(ft.Results.List) > 0 {
-		return inferExprType(ft.Results.List[0].Type)
-	}
-	return funType
-}
-
-func inferIndexType(index *ast.IndexExpr) ast.Expr {
-	if selector, ok := index.X.(*ast.SelectorExpr); ok && selector.Sel != nil && selector.Sel.Name == "Seq" {
-		if ident, ok := selector.X.(*ast.Ident); ok && ident.Name == "iter" {
-			return &ast.FuncType{
-				Params: &ast.FieldList{List: []*ast.Field{{
-					Names: []*ast.Ident{{Name: "yield"}},
-					Type: &ast.FuncType{
-						Params:  &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("V")}}},
-						Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("bool")}}},
-					},
-				}}},
-			}
+		if core == nil {
+			core = embeddedCore
+		} else if !types.Identical(core, embeddedCore) {
+			return nil
 		}
 	}
-
-	switch xType := inferExprType(index.X).(type) {
-	case *ast.ArrayType:
-		return inferExprType(xType.Elt)
-	case *ast.MapType:
-		return inferExprType(xType.Value)
-	default:
-		return xType
-	}
+	return core
 }
 
-func inferIndexListType(index *ast.IndexListExpr) ast.Expr {
-	if selector, ok := index.X.(*ast.SelectorExpr); ok && selector.Sel != nil && selector.Sel.Name == "Seq2" {
-		if ident, ok := selector.X.(*ast.Ident); ok && ident.Name == "iter" {
-			return &ast.FuncType{
-				Params: &ast.FieldList{List: []*ast.Field{{
-					Names: []*ast.Ident{{Name: "yield"}},
-					Type: &ast.FuncType{
-						Params: &ast.FieldList{List: []*ast.Field{
-							{Type: ast.NewIdent("K")},
-							{Type: ast.NewIdent("V")},
-						}},
-						Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("bool")}}},
-					},
-				}}},
-			}
-		}
-	}
-
-	xType := inferExprType(index.X)
-	if at, ok := xType.(*ast.ArrayType); ok {
-		return inferExprType(at.Elt)
-	}
-	return xType
+// isSliceType reports whether t is a slice, including named slice types,
+// aliases and type parameters constrained to slices.
+func isSliceType(t types.Type) bool {
+	_, ok := coreType(t).(*types.Slice)
+	return ok
 }
 
-func inferSelectorType(sel *ast.SelectorExpr) ast.Expr {
-	xType := inferExprType(sel.X)
-	if se, ok := xType.(*ast.StarExpr); ok {
-		xType = se.X
-	}
-	switch x := xType.(type) {
-	case *ast.StructType:
-		for _, field := range x.Fields.List {
-			for _, name := range field.Names {
-				if name.Name == sel.Sel.Name {
-					return inferExprType(field.Type)
-				}
-			}
-		}
-	case *ast.InterfaceType:
-		for _, method := range x.Methods.List {
-			for _, name := range method.Names {
-				if name.Name == sel.Sel.Name {
-					return inferExprType(method.Type)
-				}
-			}
-		}
-	}
-	return nil
+func (v *returnsVisitor) isSlice(expr ast.Expr) bool {
+	return isSliceType(v.pass.TypesInfo.TypeOf(expr))
 }
 
-func inferIdentType(ident *ast.Ident) ast.Expr {
-	if ident.Obj == nil {
-		switch ident.Name {
-		case "bool", "byte", "comparable", "error", "rune", "string", "any",
-			"int", "int8", "int16", "int32", "int64",
-			"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
-			"float32", "float64", "complex64", "complex128":
-			return ident
-		case "nil":
-			return ast.NewIdent("any")
-		case "true", "false":
-			return ast.NewIdent("bool")
-		case "iota":
-			return ast.NewIdent("int")
-		}
-	} else {
-		switch decl := ident.Obj.Decl.(type) {
-		case *ast.Field:
-			return inferExprType(decl.Type)
-		case *ast.FuncDecl:
-			return inferExprType(decl.Type)
-		case *ast.TypeSpec:
-			return inferExprType(decl.Type)
-		case *ast.ValueSpec:
-			return inferValueType(decl, ident.Name)
-		case *ast.AssignStmt:
-			return inferAssignType(decl, ident.Name)
-		}
+// isBuiltin reports whether expr refers to the named builtin function,
+// taking shadowing into account.
+func (v *returnsVisitor) isBuiltin(expr ast.Expr, name string) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
 	}
-	return nil
+	builtin, ok := v.pass.TypesInfo.Uses[ident].(*types.Builtin)
+	return ok && builtin.Name() == name
 }
 
-func inferValueType(value *ast.ValueSpec, name string) ast.Expr {
-	if value.Type != nil {
-		return inferExprType(value.Type)
-	}
-
-	index := -1
-	for i := range value.Names {
-		if value.Names[i].Name == name {
-			index = i
-		}
+// builtinAt reports whether name refers to the named builtin function at pos,
+// and the Go version of the file containing pos provides it.
+func (v *returnsVisitor) builtinAt(name string, pos token.Pos) bool {
+	scope := v.pass.Pkg.Scope().Innermost(pos)
+	if scope == nil {
+		return false
 	}
-	if index < 0 {
-		return nil
+	if _, obj := scope.LookupParent(name, pos); obj != types.Universe.Lookup(name) {
+		return false
 	}
-
-	if len(value.Names) == len(value.Values) {
-		return inferExprType(value.Values[index])
+	switch name {
+	case "min", "max", "clear":
+		return v.goVersionAtLeast(pos, "go1.21")
 	}
-
-	return inferAssignMultiType(value.Values[0], index)
+	return true
 }
 
-func inferAssignType(assign *ast.AssignStmt, name string) ast.Expr {
-	index := -1
-	for i := range assign.Lhs {
-		if id, ok := assign.Lhs[i].(*ast.Ident); ok && id.Name == name {
-			index = i
-		}
-	}
-	if index < 0 {
-		return nil
-	}
-
-	if len(assign.Rhs) == 1 {
-		if ue, ok := assign.Rhs[0].(*ast.UnaryExpr); ok && ue.Op == token.RANGE {
-			switch rhsType := inferExprType(assign.Rhs[0]).(type) {
-			case *ast.ArrayType:
-				switch index {
-				case 0:
-					return ast.NewIdent("int")
-				case 1:
-					return inferExprType(rhsType.Elt)
-				}
-			case *ast.MapType:
-				switch index {
-				case 0:
-					return inferExprType(rhsType.Key)
-				case 1:
-					return inferExprType(rhsType.Value)
-				}
-			case *ast.Ident:
-				if rhsType.Name == "string" {
-					switch index {
-					case 0:
-						return ast.NewIdent("int")
-					case 1:
-						return ast.NewIdent("rune")
-					}
-				}
-			case *ast.ChanType:
-				if index == 0 {
-					return inferExprType(rhsType.Value)
-				}
-			}
-		}
-	}
-
-	if len(assign.Lhs) == len(assign.Rhs) {
-		return inferExprType(assign.Rhs[index])
+// goVersionAtLeast reports whether the Go version of the file containing pos
+// is at least the given one, assuming so if unknown.
+func (v *returnsVisitor) goVersionAtLeast(pos token.Pos, goVersion string) bool {
+	fileVersion := v.pass.Pkg.GoVersion()
+	if file := v.file(pos); file != nil && v.pass.TypesInfo.FileVersions[file] != "" {
+		fileVersion = v.pass.TypesInfo.FileVersions[file]
 	}
+	return fileVersion == "" || version.Compare(fileVersion, goVersion) >= 0
+}
 
-	return inferAssignMultiType(assign.Rhs[0], index)
+func (v *returnsVisitor) isNil(expr ast.Expr) bool {
+	return v.pass.TypesInfo.Types[expr].IsNil()
 }
 
-func inferAssignMultiType(rhs ast.Expr, index int) ast.Expr {
-	switch rhs := rhs.(type) {
-	case *ast.TypeAssertExpr:
-		switch index {
-		case 0:
-			return inferExprType(rhs.Type)
-		case 1:
-			return ast.NewIdent("bool")
-		}
-	case *ast.CallExpr:
-		if ft, ok := inferExprType(rhs.Fun).(*ast.FuncType); ok {
-			for _, res := range ft.Results.List {
-				for range res.Names {
-					if index == 0 {
-						return inferExprType(res.Type)
-					}
-					index--
-				}
-			}
-		}
-	case *ast.IndexExpr:
-		if mt, ok := inferExprType(rhs.X).(*ast.MapType); ok {
-			switch index {
-			case 0:
-				return inferExprType(mt.Value)
-			case 1:
-				return ast.NewIdent("bool")
-			}
-		}
-	case *ast.UnaryExpr:
-		if ct, ok := inferExprType(rhs.X).(*ast.ChanType); ok {
-			switch index {
-			case 0:
-				return inferExprType(ct.Value)
-			case 1:
-				return ast.NewIdent("bool")
-			}
-		}
+// maxInt returns the largest capacity make accepts on the target platform.
+func (v *returnsVisitor) maxInt() int {
+	size := int64(8)
+	if v.pass.TypesSizes != nil {
+		size = v.pass.TypesSizes.Sizeof(types.Typ[types.Int])
 	}
-
-	return nil
+	if size >= 8 {
+		return int(^uint(0) >> 1)
+	}
+	return 1<<(8*size-1) - 1
 }
@@ -0,0 +1,362 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// trackedSlice returns the declaration of the in-scope slice that expr refers to, if any.
+func (v *returnsVisitor) trackedSlice(expr ast.Expr) *sliceDeclaration {
+	root, steps, ok := v.path(expr)
+	if !ok {
+		return nil
+	}
+	if steps == "" {
+		return v.findSlice(root)
+	}
+	obj, ok := v.paths[pathKey{root: root, steps: steps}]
+	if !ok {
+		return nil
+	}
+	return v.findSlice(obj)
+}
+
+// building reports whether expr refers to an in-scope slice that may be
+// appended to, unlike a slice the function did not make before it is.
+func (v *returnsVisitor) building(expr ast.Expr) bool {
+	sliceDecl := v.trackedSlice(expr)
+	if sliceDecl == nil {
+		return false
+	}
+	return sliceDecl.kind != growKind || sliceDecl.pending != nil || sliceDecl.loop != nil
+}
+
+// touch records why a slice cannot be preallocated should a later loop append
+// to it, for the slice that expr refers to and any selected from it.
+func (v *returnsVisitor) touch(expr ast.Expr, reason string) {
+	for _, sliceDecl := range v.within(expr) {
+		if sliceDecl.touched == "" {
+			sliceDecl.touched = reason
+		}
+	}
+}
+
+// fill records that the elements of the slice indexed by expr are written to,
+// e.g. `x[i] = v`.
+func (v *returnsVisitor) fill(expr ast.Expr) {
+	index, ok := ast.Unparen(expr).(*ast.IndexExpr)
+	if !ok {
+		return
+	}
+	if sliceDecl := v.trackedSlice(index.X); sliceDecl != nil {
+		sliceDecl.filled = true
+	}
+}
+
+// selfAppend reports whether expr appends to the given slice, e.g. `x = append(x, ...)`.
+func (v *returnsVisitor) selfAppend(lhs, expr ast.Expr) (*ast.CallExpr, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || !v.isBuiltin(call.Fun, "append") || len(call.Args) == 0 {
+		return nil, false
+	}
+	sliceDecl := v.trackedSlice(lhs)
+	return call, sliceDecl != nil && sliceDecl == v.trackedSlice(call.Args[0])
+}
+
+// touches records the uses of in-scope slices within node that could observe
+// or alias their backing array, or change their length other than by
+// appending to themselves. Uses that only read the slice are ignored.
+func (v *returnsVisitor) touches(node ast.Node) {
+	if node == nil || len(v.sliceDeclarations) == 0 {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// the closure may run any number of times, at any point
+			ast.Inspect(n.Body, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok {
+					v.touch(ident, "captured by a closure")
+				}
+				return true
+			})
+			return false
+
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				var rhs ast.Expr
+				if len(n.Lhs) == len(n.Rhs) {
+					rhs = n.Rhs[i]
+				}
+				if call, ok := v.selfAppend(lhs, rhs); ok {
+					// counted as an append rather than a use
+					v.appendArgs(call)
+					continue
+				}
+				v.fill(lhs)
+				if len(v.within(lhs)) > 0 {
+					v.touch(lhs, "reassigned")
+				} else {
+					v.touches(lhs)
+				}
+				v.touches(rhs)
+			}
+			if len(n.Lhs) != len(n.Rhs) {
+				for _, rhs := range n.Rhs {
+					v.touches(rhs)
+				}
+			}
+			return false
+
+		case *ast.ReturnStmt:
+			// returning the slice leaves any later loop unreached
+			for _, result := range n.Results {
+				if call, ok := result.(*ast.CallExpr); ok && v.isBuiltin(call.Fun, "append") &&
+					len(call.Args) > 0 && v.trackedSlice(call.Args[0]) != nil {
+					// e.g., `return append(x, y)`, counted as an append
+					v.appendArgs(call)
+					continue
+				}
+				if len(v.within(result)) == 0 {
+					v.touches(result)
+				}
+			}
+			return false
+
+		case *ast.IncDecStmt:
+			v.fill(n.X)
+
+		case *ast.RangeStmt:
+			if v.trackedSlice(n.X) == nil {
+				v.touches(n.X)
+			}
+			v.touches(n.Key)
+			v.touches(n.Value)
+			v.touches(n.Body)
+			return false
+
+		case *ast.CallExpr:
+			switch {
+			case v.isBuiltin(n.Fun, "len"), v.isBuiltin(n.Fun, "cap"), v.isBuiltin(n.Fun, "copy"):
+				if v.isBuiltin(n.Fun, "copy") && len(n.Args) > 0 {
+					if sliceDecl := v.trackedSlice(n.Args[0]); sliceDecl != nil {
+						sliceDecl.filled = true
+					}
+				}
+				for _, arg := range n.Args {
+					if v.trackedSlice(arg) == nil {
+						v.touches(arg)
+					}
+				}
+				return false
+			case v.isBuiltin(n.Fun, "append"):
+				if len(n.Args) > 0 {
+					v.touch(n.Args[0], "aliased by an append to another slice")
+					v.touches(n.Args[0])
+				}
+				v.appendArgs(n)
+				return false
+			}
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && isBuilderMethod(sel.Sel.Name) && v.isBuilder(sel.X) && v.trackedSlice(sel.X) != nil {
+				// writing to or reading a builder
+				for _, arg := range n.Args {
+					v.touch(arg, "passed to a function")
+					v.touches(arg)
+				}
+				return false
+			}
+			for _, arg := range n.Args {
+				v.touch(arg, "passed to a function")
+			}
+
+		case *ast.IndexExpr:
+			if v.trackedSlice(n.X) != nil {
+				v.touches(n.Index)
+				return false
+			}
+			if v.isPathExpr(n) {
+				// e.g., `parts[0]`
+				v.touch(n, "aliased")
+				return false
+			}
+
+		case *ast.SelectorExpr, *ast.StarExpr:
+			if expr := n.(ast.Expr); v.isPathExpr(expr) {
+				// e.g., `res.Rows`, or another field such as `res.Name`
+				v.touch(expr, "aliased")
+				return false
+			}
+
+		case *ast.BinaryExpr:
+			// comparing the slice to nil only reads it
+			if (n.Op == token.EQL || n.Op == token.NEQ) && v.isNil(n.Y) && v.trackedSlice(n.X) != nil {
+				return false
+			}
+			if (n.Op == token.EQL || n.Op == token.NEQ) && v.isNil(n.X) && v.trackedSlice(n.Y) != nil {
+				return false
+			}
+
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				v.touch(n.X, "address taken")
+			}
+
+		case *ast.SliceExpr:
+			v.touch(n.X, "resliced")
+
+		case *ast.Ident:
+			v.touch(n, "aliased")
+		}
+		return true
+	})
+}
+
+// appendArgs records the uses of slices within the elements of an append,
+// where spreading a slice only reads it.
+func (v *returnsVisitor) appendArgs(call *ast.CallExpr) {
+	for i, arg := range call.Args {
+		if i == 0 {
+			continue
+		}
+		if i == len(call.Args)-1 && call.Ellipsis.IsValid() && v.trackedSlice(arg) != nil {
+			continue
+		}
+		v.touches(arg)
+	}
+}
+
+// foldAppends adds the elements appended to in-scope slices, inserted into
+// in-scope maps, or written to in-scope builders, by an assignment outside of
+// any loop to the capacity of the next loop appending to them, or of the last
+// one if none follows.
+func (v *returnsVisitor) foldAppends(stmt *ast.AssignStmt) {
+	if len(stmt.Rhs) == 1 {
+		// e.g., `n, err := sb.WriteString(s)`
+		v.foldWrite(stmt.Rhs[0])
+	}
+	for i, lhs := range stmt.Lhs {
+		if stmt.Tok != token.DEFINE {
+			v.foldInsert(lhs)
+		}
+		if i >= len(stmt.Rhs) {
+			break
+		}
+		call, ok := v.selfAppend(lhs, stmt.Rhs[i])
+		if !ok {
+			continue
+		}
+		v.foldAppend(v.trackedSlice(lhs), call)
+	}
+}
+
+// foldReturn adds the elements appended to in-scope slices by the results of a
+// return statement, e.g. `return append(x, y)`, to the capacity of the last
+// loop appending to them.
+func (v *returnsVisitor) foldReturn(stmt *ast.ReturnStmt) {
+	for _, result := range stmt.Results {
+		call, ok := result.(*ast.CallExpr)
+		if !ok || !v.isBuiltin(call.Fun, "append") || len(call.Args) == 0 {
+			continue
+		}
+		if sliceDecl := v.trackedSlice(call.Args[0]); sliceDecl != nil && sliceDecl.loop != nil {
+			v.foldAppend(sliceDecl, call)
+		}
+	}
+}
+
+// foldAppend adds the elements appended by a call to the capacity of the slice.
+func (v *returnsVisitor) foldAppend(sliceDecl *sliceDeclaration, call *ast.CallExpr) {
+	if sliceDecl.touched != "" {
+		// appends after another use are not counted, as the slice may
+		// no longer be backed by the array it was made with
+		return
+	}
+
+	var n ast.Expr
+	switch {
+	case call.Ellipsis.IsValid():
+		// e.g., `x = append(x, y...)`
+		if n = v.spreadLen(call.Args[1]); n == nil {
+			v.touch(call.Args[0], "appended an unknown number of elements")
+			return
+		}
+	case len(call.Args) < 2:
+		return
+	default:
+		n = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(call.Args) - 1)}
+	}
+
+	sliceDecl.pending = exprIntAdd(sliceDecl.pending, n)
+	if v.depth > sliceDecl.depth {
+		// the append may not happen
+		sliceDecl.pendingUpperBound = true
+	}
+}
+
+// spreadLen returns the number of elements spread by `x...`, or nil if unknown.
+func (v *returnsVisitor) spreadLen(expr ast.Expr) ast.Expr {
+	tv := v.pass.TypesInfo.Types[expr]
+	if tv.Value != nil && tv.Value.Kind() == constant.String {
+		return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(constant.StringVal(tv.Value)))}
+	}
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.SliceExpr:
+		// e.g., `arr[:]` of a fixed-size array
+		if e.Low != nil || e.High != nil {
+			break
+		}
+		t := coreType(v.pass.TypesInfo.TypeOf(e.X))
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = coreType(ptr.Elem())
+		}
+		if arr, ok := t.(*types.Array); ok {
+			return &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(arr.Len(), 10)}
+		}
+	case *ast.CallExpr:
+		// e.g., `[]byte(s)`, but not `[]rune(s)`
+		if len(e.Args) == 1 && v.pass.TypesInfo.Types[e.Fun].IsType() && isByteSlice(v.pass.TypesInfo.TypeOf(e.Fun)) {
+			if basic, ok := coreType(v.pass.TypesInfo.TypeOf(e.Args[0])).(*types.Basic); ok && basic.Info()&types.IsString != 0 {
+				return v.spreadLen(e.Args[0])
+			}
+		}
+	}
+	switch t := coreType(tv.Type).(type) {
+	case *types.Slice:
+	case *types.Basic:
+		if t.Info()&types.IsString == 0 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if !isPath(expr) || v.building(expr) {
+		// length cannot be evaluated at the declaration
+		return nil
+	}
+	return &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{expr}}
+}
+
+// isByteSlice reports whether t is a slice of bytes.
+func isByteSlice(t types.Type) bool {
+	slice, ok := coreType(t).(*types.Slice)
+	if !ok {
+		return false
+	}
+	elem, ok := types.Unalias(slice.Elem()).(*types.Basic)
+	return ok && elem.Kind() == types.Byte
+}
+
+// isPath reports whether expr is a variable or a field selected from one,
+// such that evaluating it again has no side effects.
+func isPath(expr ast.Expr) bool {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isPath(e.X)
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// collectWhileInits records the statement preceding each loop with only a
+// condition, e.g. `i := 0` in `i := 0; for i < n { ...; i++ }`.
+func collectWhileInits(file *ast.File) map[*ast.ForStmt]ast.Stmt {
+	inits := make(map[*ast.ForStmt]ast.Stmt)
+	ast.Inspect(file, func(node ast.Node) bool {
+		var list []ast.Stmt
+		switch n := node.(type) {
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		}
+		for i := 1; i < len(list); i++ {
+			if loop, ok := unlabel(list[i]).(*ast.ForStmt); ok && loop.Init == nil && loop.Cond != nil && loop.Post == nil {
+				inits[loop] = list[i-1]
+			}
+		}
+		return true
+	})
+	return inits
+}
+
+// whileLoop returns the three-clause equivalent of a loop with only a
+// condition, whose counter is initialized by the preceding statement and
+// advanced exactly once on every path through the body, or nil if it has none.
+func (v *returnsVisitor) whileLoop(stmt *ast.ForStmt) *ast.ForStmt {
+	init := whileLoopInit(v.whileInits[stmt])
+	if init == nil {
+		return nil
+	}
+
+	var post ast.Stmt
+	var step forLoopStep
+	for _, bodyStmt := range stmt.Body.List {
+		s, ok := parseForLoopStep(bodyStmt)
+		if !ok || !assignsName(init, s.name) {
+			continue
+		}
+		if post != nil {
+			// advanced more than once
+			return nil
+		}
+		post, step = bodyStmt, s
+	}
+	if post == nil || !v.advancedOnce(stmt.Body, post, step.ident) {
+		return nil
+	}
+
+	return &ast.ForStmt{For: stmt.For, Init: init, Cond: stmt.Cond, Post: post, Body: stmt.Body}
+}
+
+// advancedOnce reports whether the post statement is the only write to the
+// counter in the loop body, and no continue can skip it.
+func (v *returnsVisitor) advancedOnce(body *ast.BlockStmt, post ast.Stmt, counter *ast.Ident) bool {
+	obj := v.pass.TypesInfo.Uses[counter]
+	isCounter := func(expr ast.Expr) bool {
+		ident, ok := ast.Unparen(expr).(*ast.Ident)
+		return ok && obj != nil && v.pass.TypesInfo.Uses[ident] == obj
+	}
+
+	once := true
+	var visit func(node ast.Node, nested bool)
+	visit = func(node ast.Node, nested bool) {
+		if node == nil {
+			return
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			if !once || n == post {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				for _, lhs := range n.Lhs {
+					once = once && !isCounter(lhs)
+				}
+			case *ast.IncDecStmt:
+				once = once && !isCounter(n.X)
+			case *ast.UnaryExpr:
+				once = once && (n.Op != token.AND || !isCounter(n.X))
+			case *ast.RangeStmt:
+				if n.Tok == token.ASSIGN {
+					once = once && !isCounter(n.Key) && !isCounter(n.Value)
+				}
+				visit(n.Body, true)
+				return false
+			case *ast.ForStmt:
+				visit(n.Init, true)
+				visit(n.Post, true)
+				visit(n.Body, true)
+				return false
+			case *ast.BranchStmt:
+				if n.Tok == token.CONTINUE && n.Pos() < post.Pos() && (!nested || n.Label != nil) {
+					// skips the rest of the iteration
+					once = false
+				}
+			}
+			return true
+		})
+	}
+	visit(body, false)
+	return once
+}
+
+// whileLoopInit returns the statement preceding a loop as an assignment, if
+// it initializes variables.
+func whileLoopInit(stmt ast.Stmt) *ast.AssignStmt {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if s.Tok == token.DEFINE || s.Tok == token.ASSIGN {
+			return s
+		}
+	case *ast.DeclStmt:
+		genD, ok := s.Decl.(*ast.GenDecl)
+		if !ok || genD.Tok != token.VAR || len(genD.Specs) != 1 {
+			return nil
+		}
+		spec := genD.Specs[0].(*ast.ValueSpec)
+		lhs := make([]ast.Expr, len(spec.Names))
+		for i, name := range spec.Names {
+			lhs[i] = name
+		}
+		return &ast.AssignStmt{Lhs: lhs, Tok: token.DEFINE, Rhs: spec.Values}
+	}
+	return nil
+}
+
+func assignsName(init *ast.AssignStmt, name string) bool {
+	for _, lhs := range init.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == name {
+			return true
+		}
+	}
+	return false
+}
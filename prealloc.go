@@ -11,9 +11,7 @@ import (
 )
 
 // Support: (in order of priority)
-//  * Full make suggestion with type?
 //	* Test flag
-//  * Embedded ifs?
 //  * Use an import rather than the duplicated import.go
 
 func main() {
@@ -24,6 +22,7 @@ type prealloc struct {
 	simple            bool
 	includeRangeLoops bool
 	includeForLoops   bool
+	includeMaps       bool
 }
 
 func NewAnalyzer() *analysis.Analyzer {
@@ -41,14 +40,20 @@ func NewAnalyzer() *analysis.Analyzer {
 		Run:  p.run,
 	}
 	a.Flags.Init("prealloc", flag.ExitOnError)
-	a.Flags.BoolVar(&p.simple, "simple", true, "Report preallocation suggestions only on simple loops that have no returns/breaks/continues/gotos in them")
+	a.Flags.BoolVar(&p.simple, "simple", true, "Report preallocation suggestions only on simple loops that have no returns/breaks/gotos/panics that exit them")
 	a.Flags.BoolVar(&p.includeRangeLoops, "rangeloops", true, "Report preallocation suggestions on range loops")
 	a.Flags.BoolVar(&p.includeForLoops, "forloops", false, "Report preallocation suggestions on for loops")
+	a.Flags.BoolVar(&p.includeMaps, "maps", false, "Report size hint suggestions on maps filled by loops")
 	return a
 }
 
 func (p *prealloc) run(pass *analysis.Pass) (any, error) {
-	hints := pkg.Check(pass.Files, p.simple, p.includeRangeLoops, p.includeForLoops)
+	hints := pkg.Check(pass, pkg.Options{
+		Simple:     p.simple,
+		RangeLoops: p.includeRangeLoops,
+		ForLoops:   p.includeForLoops,
+		Maps:       p.includeMaps,
+	})
 
 	for _, hint := range hints {
 		pass.Report(hint)
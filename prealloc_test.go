@@ -19,7 +19,8 @@ func TestCheckForPreallocations(t *testing.T) {
 
 	a := NewAnalyzer()
 	_ = a.Flags.Set("forloops", "true")
-	analysistest.Run(t, filepath.Join(wd, "testdata"), a, ".")
+	_ = a.Flags.Set("maps", "true")
+	analysistest.RunWithSuggestedFixes(t, filepath.Join(wd, "testdata"), a, ".")
 }
 
 func BenchmarkSize10NoPreallocate(b *testing.B) {
@@ -0,0 +1,179 @@
+package test
+
+// appends inside branches are counted across every path through the loop body
+
+func ok(int) bool { return true }
+
+func branchFilter(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		if ok(v) {
+			x = append(x, v)
+		}
+	}
+}
+
+func branchIfElse(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		if ok(v) {
+			x = append(x, v)
+		} else {
+			x = append(x, -v)
+		}
+	}
+}
+
+func branchIfElseUneven(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most 2 \\* len\\(a\\)$"
+	for _, v := range a {
+		if ok(v) {
+			x = append(x, v, v)
+		} else {
+			x = append(x, -v)
+		}
+	}
+}
+
+func branchIfElseIf(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		if v < 0 {
+			x = append(x, -v)
+		} else if v > 0 {
+			x = append(x, v)
+		} else {
+			x = append(x, 0)
+		}
+	}
+}
+
+func branchNestedIf(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		if v > 0 {
+			if ok(v) {
+				x = append(x, v)
+			}
+		}
+	}
+}
+
+func branchSequential(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most 2 \\* len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+		if ok(v) {
+			x = append(x, v)
+		}
+	}
+}
+
+func branchSwitchDefault(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		switch v {
+		case 0:
+			x = append(x, v)
+		case 1, 2:
+			x = append(x, v*2)
+		default:
+			x = append(x, -v)
+		}
+	}
+}
+
+func branchSwitchNoDefault(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		switch {
+		case v < 0:
+			x = append(x, -v)
+		case v > 0:
+			x = append(x, v)
+		}
+	}
+}
+
+func branchSwitchBreak(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		switch {
+		case v < 0:
+			if !ok(v) {
+				break
+			}
+			x = append(x, -v)
+		default:
+			x = append(x, v)
+		}
+	}
+}
+
+func branchSwitchFallthrough(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most 2 \\* len\\(a\\)$"
+	for _, v := range a {
+		switch {
+		case v < 0:
+			x = append(x, v)
+			fallthrough
+		default:
+			x = append(x, v)
+		}
+	}
+}
+
+func branchTypeSwitch(a []any) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		switch v := v.(type) {
+		case int:
+			x = append(x, v)
+		case int64:
+			x = append(x, int(v))
+		}
+	}
+}
+
+func branchSelect(a []int, ch chan int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		select {
+		case w := <-ch:
+			x = append(x, w)
+		case ch <- v:
+			x = append(x, v)
+		}
+	}
+}
+
+func branchSelectDefault(a []int, ch chan int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		select {
+		case ch <- v:
+		default:
+			x = append(x, v)
+		}
+	}
+}
+
+func branchNestedLoop(a [][]int) {
+	var x []int // want "Consider preallocating x using a counting pre-pass, as each iteration appends 1 \\+ len\\(row\\) elements$"
+	for _, row := range a {
+		x = append(x, len(row))
+		for _, v := range row {
+			x = append(x, v)
+		}
+	}
+}
+
+func branchNoAppend(a []int) {
+	var x []int
+	for _, v := range a {
+		if ok(v) {
+			_ = v
+		}
+	}
+	_ = x
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+)
+
+// strings.Builder and bytes.Buffer written to by loops
+
+func builderSeparator(a []int, sep string) string {
+	var sb strings.Builder // want "Consider preallocating sb with capacity len\\(a\\) \\* len\\(sep\\) by growing it before the loop$"
+	for range a {
+		sb.WriteString(sep)
+	}
+	return sb.String()
+}
+
+func builderSeparatorBetween(a []string, sep string) string {
+	var sb strings.Builder // want "Consider preallocating sb with capacity max\\(0, len\\(a\\)-1\\) \\* len\\(sep\\) by growing it before the loop$"
+	for i := 1; i < len(a); i++ {
+		sb.WriteString(sep)
+	}
+	return sb.String()
+}
+
+func builderLiteral(n int) string {
+	sb := strings.Builder{} // want "Consider preallocating sb with capacity 3 \\* n by growing it before the loop$"
+	for i := 0; i < n; i++ {
+		sb.WriteString("abc")
+	}
+	return sb.String()
+}
+
+func bufferBytes(s []byte) []byte {
+	var buf bytes.Buffer // want "Consider preallocating buf with capacity len\\(s\\) by growing it before the loop$"
+	for _, c := range s {
+		buf.WriteByte(c)
+	}
+	return buf.Bytes()
+}
+
+func bufferRunes(s []rune) string {
+	var buf bytes.Buffer // want "Consider preallocating buf with capacity at most len\\(s\\) \\* utf8.UTFMax by growing it before the loop$"
+	for _, r := range s {
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func builderConstRune(s []string) string {
+	var sb strings.Builder // want "Consider preallocating sb with capacity 3 \\* len\\(s\\) by growing it before the loop$"
+	for range s {
+		sb.WriteRune('€')
+	}
+	return sb.String()
+}
+
+func builderMixed(a []int, sep []byte) []byte {
+	var buf bytes.Buffer // want "Consider preallocating buf with capacity len\\(a\\) \\+ len\\(a\\)\\*len\\(sep\\) by growing it before the loop$"
+	for range a {
+		buf.WriteByte('[')
+		buf.Write(sep)
+	}
+	return buf.Bytes()
+}
+
+func builderPrefix(a []string) string {
+	var sb strings.Builder // want "Consider preallocating sb with capacity len\\(a\\) by growing it before the loop$"
+	sb.WriteString("values: ")
+	for range a {
+		_, _ = sb.WriteString(",")
+	}
+	return sb.String()
+}
+
+func builderTwoLoops(a, b []byte) string {
+	var sb strings.Builder // want "Consider preallocating sb with capacity len\\(a\\) \\+ 1 \\+ len\\(b\\) by growing it before the loop$"
+	for _, c := range a {
+		sb.WriteByte(c)
+	}
+	sb.WriteByte('|')
+	for _, c := range b {
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func builderPerElement(a []string) string {
+	var sb strings.Builder // want "Consider preallocating sb using a counting pre-pass, as each iteration writes len\\(s\\) bytes$"
+	for _, s := range a {
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
+
+func builderUnknown(a []int) string {
+	var sb strings.Builder
+	for _, n := range a {
+		sb.WriteString(strings.Repeat("x", n))
+	}
+	return sb.String()
+}
+
+func builderAddressTaken(a []string, sep string) string {
+	var sb strings.Builder
+	for range a {
+		sb.WriteString(sep)
+		write(&sb)
+	}
+	return sb.String()
+}
+
+func builderReset(a []string, sep string) string {
+	var sb strings.Builder
+	for range a {
+		sb.WriteString(sep)
+		sb.Reset()
+	}
+	return sb.String()
+}
+
+func write(*strings.Builder) {}
@@ -0,0 +1,118 @@
+package test
+
+// slices made with an explicit capacity
+
+func capacityEnough(a []int) []int {
+	x := make([]int, 0, len(a))
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func capacityMore(a []int) []int {
+	x := make([]int, 0, 2*len(a))
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func capacityTooSmall(a []int) []int {
+	x := make([]int, 0, len(a)) // want "Capacity of x is too small: made with len\\(a\\), but needs 2 \\* len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v, -v)
+	}
+	return x
+}
+
+func capacityTooSmallByOne(a []int) []int {
+	var x = make([]int, 0, len(a)) // want "Capacity of x is too small: made with len\\(a\\), but needs 1 \\+ len\\(a\\)$"
+	x = append(x, 0)
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func capacityTooSmallLength(a []int) []int {
+	x := make([]int, 1, len(a)) // want "Capacity of x is too small: made with len\\(a\\), but needs 1 \\+ len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func capacityFiltered(a []int) []int {
+	x := make([]int, 0, len(a)/2)
+	for _, v := range a {
+		if v > 0 {
+			x = append(x, v)
+		}
+	}
+	return x
+}
+
+func capacityStale(a, b []int) []int {
+	x := make([]int, 0, len(a)) // want "Capacity of x looks stale: made with len\\(a\\), but needs len\\(b\\)$"
+	for _, v := range b {
+		x = append(x, v)
+	}
+	return x
+}
+
+func capacityResliced(a []int) []int {
+	x := make([]int, 0, len(a))
+	for _, v := range a[1:] {
+		x = append(x, v)
+	}
+	return x
+}
+
+func capacityStaleFor(a, b []string) []string {
+	x := make([]string, 0, len(a)) // want "Capacity of x looks stale: made with len\\(a\\), but needs len\\(b\\)$"
+	for i := 0; i < len(b); i++ {
+		x = append(x, b[i])
+	}
+	return x
+}
+
+func capacityStaleFiltered(a, b []int) []int {
+	x := make([]int, 0, len(a))
+	for _, v := range b {
+		if v > 0 {
+			x = append(x, v)
+		}
+	}
+	return x
+}
+
+func capacityStaleTwice(a, b []int) []int {
+	x := make([]int, 0, len(a)) // want "Capacity of x looks stale: made with len\\(a\\), but needs 2 \\* len\\(b\\)$"
+	for _, v := range b {
+		x = append(x, v)
+	}
+	for _, v := range b {
+		x = append(x, -v)
+	}
+	return x
+}
+
+func capacityCombined(a, b []int) []int {
+	x := make([]int, 0, len(a)+len(b))
+	for _, v := range a {
+		x = append(x, v)
+	}
+	for _, v := range b {
+		x = append(x, v)
+	}
+	return x
+}
+
+func capacityVariable(a []int, n int) []int {
+	x := make([]int, 0, n)
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
@@ -0,0 +1,58 @@
+package test
+
+// statement lists inside case and comm clauses are analyzed like blocks
+
+func clauseSwitch(kind int, a []int) {
+	switch kind {
+	case 0:
+		var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+		for _, v := range a {
+			x = append(x, v)
+		}
+	default:
+		y := []int{} // want "Consider preallocating y with capacity len\\(a\\)$"
+		for _, v := range a {
+			y = append(y, v)
+		}
+	}
+}
+
+func clauseTypeSwitch(i interface{}) {
+	switch t := i.(type) {
+	case []string:
+		var x []string // want "Consider preallocating x with capacity len\\(t\\)$"
+		for _, s := range t {
+			x = append(x, s)
+		}
+	case map[string]int:
+		var keys []string // want "Consider preallocating keys with capacity len\\(t\\)$"
+		for k := range t {
+			keys = append(keys, k)
+		}
+	}
+}
+
+func clauseSelect(ch chan []int, done chan struct{}) {
+	select {
+	case a := <-ch:
+		var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+		for _, v := range a {
+			x = append(x, v)
+		}
+	case <-done:
+		var y []int // want "Consider preallocating y with capacity 10$"
+		for i := 0; i < 10; i++ {
+			y = append(y, i)
+		}
+	}
+}
+
+func clauseDeclaredOutside(kind int, a []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	switch kind {
+	case 0:
+		for _, v := range a {
+			x = append(x, v)
+		}
+	}
+}
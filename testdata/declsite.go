@@ -0,0 +1,143 @@
+package test
+
+// capacities must compile where the slice is declared
+
+func declSiteLaterVar(a []int) {
+	// want +1 "Consider preallocating x with capacity n by moving its declaration down to the loop$"
+	var x []int
+	n := len(a)
+	for i := range n {
+		x = append(x, i)
+	}
+	_ = x
+}
+
+func declSiteLaterVarDefine(a []int) {
+	x := []int{} // want "Consider preallocating x with capacity n by moving its declaration down to the loop$"
+	n := len(a) / 2
+	for i := 0; i < n; i++ {
+		x = append(x, a[i])
+	}
+	_ = x
+}
+
+func declSiteReferenced(a []int) {
+	var x []int // want "Consider preallocating x with capacity n by making it before the loop$"
+	n := len(x) + len(a)
+	for i := range n {
+		x = append(x, i)
+	}
+}
+
+func declSiteNested(a []int, cond bool) {
+	var x []int // want "Consider preallocating x with capacity at most n by making it before the loop$"
+	if cond {
+		n := len(a)
+		for i := range n {
+			x = append(x, i)
+		}
+	}
+}
+
+func declSiteShadowed(a []int) {
+	n := 10
+	var x []int // want "Consider preallocating x with capacity n by making it before the loop$"
+	{
+		n := len(a)
+		for i := range n {
+			x = append(x, i)
+		}
+	}
+	_ = n
+}
+
+func declSiteWrittenBetween(a []int) {
+	n := len(a)
+	var x []int // want "Consider preallocating x with capacity n by moving its declaration down to the loop$"
+	n = n * 2
+	for i := range n {
+		x = append(x, i)
+	}
+	_ = x
+}
+
+func declSiteAppendedBetween(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) by moving its declaration down to the loop$"
+	a = append(a, 0)
+	for _, v := range a {
+		x = append(x, v)
+	}
+	_ = x
+}
+
+func declSiteWrittenBetweenLoops(a []int) {
+	var x []int // want "Consider preallocating x$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	a = append(a, 0)
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func declSiteAssignedBefore(a []int, cond bool) {
+	var x []int // want "Consider preallocating x with capacity at most 1 \\+ len\\(a\\) by making it before the loop$"
+	x = append(x, 0)
+	if cond {
+		a := a[1:]
+		for _, v := range a {
+			x = append(x, v)
+		}
+	}
+}
+
+func declSiteReceived(ch chan []int) {
+	var x []int // want "Consider preallocating x$"
+	for _, v := range <-ch {
+		x = append(x, v)
+	}
+}
+
+func declSiteTyped(n uint64) {
+	var x []int // want "Consider preallocating x with capacity n$"
+	for i := range n {
+		x = append(x, int(i))
+	}
+}
+
+func declSiteTypedSum(n uint64, m uint8) {
+	var x []int // want "Consider preallocating x with capacity int\\(n\\) \\+ int\\(m\\)$"
+	for i := range n {
+		x = append(x, int(i))
+	}
+	for i := uint8(0); i < m; i++ {
+		x = append(x, int(i))
+	}
+}
+
+func declSiteTypedProduct(a [][]int, n int32) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) \\* int\\(n\\)$"
+	for range a {
+		for i := range n {
+			x = append(x, int(i))
+		}
+	}
+}
+
+func declSiteSelfRange() {
+	var x []int
+	for _, v := range x {
+		x = append(x, v)
+	}
+	_ = x
+}
+
+func declSiteSelfRangeMade() {
+	x := make([]int, 0, 4)
+	x = append(x, 1, 2)
+	for _, v := range x {
+		x = append(x, v)
+	}
+	_ = x
+}
@@ -0,0 +1,97 @@
+package test
+
+// appends spreading a slice or string of known length
+
+func ellipsisFlatten(parts [][]int) []int {
+	var out []int // want "Consider preallocating out using a counting pre-pass, as each iteration appends len\\(p\\) elements$"
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func ellipsisSeparator(a []int, sep []int) []int {
+	var out []int // want "Consider preallocating out with capacity len\\(a\\)\\*len\\(sep\\) \\+ len\\(a\\)$"
+	for _, v := range a {
+		out = append(out, sep...)
+		out = append(out, v)
+	}
+	return out
+}
+
+func ellipsisString(a []string) []byte {
+	var out []byte // want "Consider preallocating out with capacity 2 \\* len\\(a\\)$"
+	for range a {
+		out = append(out, ", "...)
+	}
+	return out
+}
+
+func ellipsisStringConversion(a []int, s string) []byte {
+	var out []byte // want "Consider preallocating out with capacity len\\(a\\) \\* len\\(s\\)$"
+	for range a {
+		out = append(out, []byte(s)...)
+	}
+	return out
+}
+
+func ellipsisArray(a []int) []int {
+	var arr [3]int
+	var out []int // want "Consider preallocating out with capacity 3 \\* len\\(a\\)$"
+	for range a {
+		out = append(out, arr[:]...)
+	}
+	return out
+}
+
+func ellipsisArrayPointer(a []int, arr *[4]byte) []byte {
+	var out []byte // want "Consider preallocating out with capacity 4 \\* len\\(a\\)$"
+	for range a {
+		out = append(out, arr[:]...)
+	}
+	return out
+}
+
+func ellipsisConditional(a []int, sep []int) []int {
+	var out []int // want "Consider preallocating out with capacity at most len\\(a\\) \\* len\\(sep\\)$"
+	for i := range a {
+		if i > 0 {
+			out = append(out, sep...)
+		}
+	}
+	return out
+}
+
+func ellipsisReassigned(a, b []int, next func() []int) []int {
+	var out []int // want "Consider preallocating out using a counting pre-pass, as each iteration appends len\\(b\\) elements$"
+	for range a {
+		b = next()
+		out = append(out, b...)
+	}
+	return out
+}
+
+func ellipsisRunes(a []int, s string) []rune {
+	var out []rune
+	for range a {
+		out = append(out, []rune(s)...)
+	}
+	return out
+}
+
+func ellipsisUnknown(a []int, next func() []int) []int {
+	var out []int
+	for range a {
+		out = append(out, next()...)
+	}
+	return out
+}
+
+func ellipsisEmpty(a []int) []byte {
+	var out []byte // want "Consider preallocating out with capacity len\\(a\\)$"
+	for _, v := range a {
+		out = append(out, ""...)
+		out = append(out, byte(v))
+	}
+	return out
+}
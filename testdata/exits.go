@@ -0,0 +1,184 @@
+package test
+
+import (
+	"log"
+	"os"
+)
+
+// only paths that leave the loop under analysis disqualify it in simple mode
+
+func exitBreakNestedIf(a []int) {
+	var x []int
+	for _, v := range a {
+		if v > 0 {
+			if ok(v) {
+				break
+			}
+		}
+		x = append(x, v)
+	}
+}
+
+func exitReturnInSwitch(a []int) {
+	var x []int
+	for _, v := range a {
+		switch v {
+		case 0:
+			return
+		}
+		x = append(x, v)
+	}
+}
+
+func exitBreakSwitch(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		switch v {
+		case 0:
+			break
+		}
+		x = append(x, v)
+	}
+}
+
+func exitBreakSelect(a []int, ch chan int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		select {
+		case ch <- v:
+			break
+		default:
+		}
+		x = append(x, v)
+	}
+}
+
+func exitBreakInnerLoop(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		for i := range v {
+			if i > 3 {
+				break
+			}
+		}
+		x = append(x, v)
+	}
+}
+
+func exitBreakOuterLoop(a [][]int) {
+outer:
+	for _, row := range a {
+		var x []int
+		for _, v := range row {
+			if v < 0 {
+				break outer
+			}
+			x = append(x, v)
+		}
+		_ = x
+	}
+}
+
+func exitContinueOuterLoop(a [][]int) {
+outer:
+	for _, row := range a {
+		var x []int
+		for _, v := range row {
+			if v < 0 {
+				continue outer
+			}
+			x = append(x, v)
+		}
+		_ = x
+	}
+}
+
+func exitGoto(a []int) {
+	var x []int
+	for _, v := range a {
+		if v < 0 {
+			goto done
+		}
+		x = append(x, v)
+	}
+done:
+}
+
+func exitPanic(a []int) {
+	var x []int
+	for _, v := range a {
+		if v < 0 {
+			panic("negative")
+		}
+		x = append(x, v)
+	}
+}
+
+func exitOsExit(a []int) {
+	var x []int
+	for _, v := range a {
+		if v < 0 {
+			os.Exit(1)
+		}
+		x = append(x, v)
+	}
+}
+
+func exitLogFatal(a []int) {
+	var x []int
+	for _, v := range a {
+		if v < 0 {
+			log.Fatalf("negative %d", v)
+		}
+		x = append(x, v)
+	}
+}
+
+func exitReturnInFuncLit(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		f := func() int {
+			if v < 0 {
+				return -v
+			}
+			return v
+		}
+		x = append(x, f())
+	}
+}
+
+func exitContinue(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		if v < 0 {
+			continue
+		}
+		x = append(x, v)
+	}
+}
+
+func exitContinueNested(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most 2 \\* len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+		switch {
+		case v < 0:
+			if !ok(v) {
+				continue
+			}
+		}
+		x = append(x, v)
+	}
+}
+
+func exitContinueInnerLoop(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		for i := range v {
+			if i > 3 {
+				continue
+			}
+		}
+		x = append(x, v)
+	}
+}
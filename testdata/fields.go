@@ -0,0 +1,156 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// slices held by struct fields, pointers and array elements
+
+type fieldsRow struct {
+	ID int
+}
+
+type fieldsResult struct {
+	Name  string
+	Rows  []fieldsRow
+	Names []string
+	raw   json.RawMessage
+}
+
+func fieldsVar(ids []int) fieldsResult {
+	var res fieldsResult // want "Consider preallocating res.Rows with capacity len\\(ids\\) by making it before the loop$"
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res
+}
+
+func fieldsLiteral(ids []int, name string) fieldsResult {
+	res := fieldsResult{Name: name} // want "Consider preallocating res.Rows with capacity len\\(ids\\) by making it before the loop$"
+	res.Name = fmt.Sprint(len(ids))
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res
+}
+
+func fieldsPointer(ids []int) *fieldsResult {
+	res := &fieldsResult{} // want "Consider preallocating res.Names with capacity len\\(ids\\) by making it before the loop$"
+	for _, id := range ids {
+		res.Names = append(res.Names, fmt.Sprint(id))
+	}
+	return res
+}
+
+func fieldsTwo(ids []int) fieldsResult {
+	var res fieldsResult // want "Consider preallocating res.Rows with capacity len\\(ids\\) by making it before the loop$" "Consider preallocating res.Names with capacity len\\(ids\\) by making it before the loop$"
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+		res.Names = append(res.Names, fmt.Sprint(id))
+	}
+	return res
+}
+
+func fieldsImported(data [][]byte) fieldsResult {
+	var res fieldsResult // want "Consider preallocating res.raw with capacity len\\(data\\) \\* len\\(sep\\) by making it before the loop$"
+	sep := []byte(",")
+	for range data {
+		res.raw = append(res.raw, sep...)
+	}
+	return res
+}
+
+func fieldsSet(ids []int) fieldsResult {
+	res := fieldsResult{Rows: []fieldsRow{}}
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res
+}
+
+func fieldsAssigned(ids []int) fieldsResult {
+	var res fieldsResult
+	res.Rows = []fieldsRow{} // want "Consider preallocating res.Rows with capacity len\\(ids\\)$"
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res
+}
+
+func fieldsPassed(ids []int, fill func(*fieldsResult)) fieldsResult {
+	var res fieldsResult
+	fill(&res)
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res
+}
+
+func fieldsReassigned(ids []int, other fieldsResult) fieldsResult {
+	var res fieldsResult
+	res = other
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res
+}
+
+func fieldsCopied(ids []int) (fieldsResult, fieldsResult) {
+	var res fieldsResult
+	res.Rows = append(res.Rows, fieldsRow{})
+	saved := res
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res, saved
+}
+
+func fieldsEarlyReturn(ids []int, err error) (fieldsResult, error) {
+	var res fieldsResult // want "Consider preallocating res.Rows with capacity len\\(ids\\) by making it before the loop$"
+	if err != nil {
+		return res, err
+	}
+	for _, id := range ids {
+		res.Rows = append(res.Rows, fieldsRow{ID: id})
+	}
+	return res, nil
+}
+
+func fieldsIndexed(ids []int) fieldsResult {
+	var res fieldsResult // want "res.Rows has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i, id := range ids {
+		res.Rows[i] = fieldsRow{ID: id}
+	}
+	return res
+}
+
+func derefReset(out *[]int, a []int) {
+	*out = nil // want "Consider preallocating \\*out with capacity len\\(a\\)$"
+	for _, v := range a {
+		*out = append(*out, v)
+	}
+}
+
+func derefNoReset(out *[]int, a []int) {
+	for _, v := range a { // want "Consider growing \\*out by len\\(a\\) with slices.Grow before the loop$"
+		*out = append(*out, v)
+	}
+}
+
+func derefMadeWithLength(out *[]int, a []int) {
+	*out = make([]int, len(a)) // want "\\*out is made with length len\\(a\\) and then appended to, so it starts with len\\(a\\) zero values$"
+	for _, v := range a {
+		*out = append(*out, v)
+	}
+}
+
+func elementReset(a []int) [2][]int {
+	var parts [2][]int
+	parts[0] = []int{} // want "Consider preallocating parts\\[0\\] with capacity len\\(a\\)$"
+	for _, v := range a {
+		parts[0] = append(parts[0], v)
+	}
+	parts[1] = append(parts[1], 0)
+	return parts
+}
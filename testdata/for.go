@@ -108,7 +108,7 @@ func forIncZeroToVarExclusive() {
 
 func forIncOneToVarExclusive() {
 	n := 5
-	var x []int // want "Consider preallocating x with capacity n - 1$"
+	var x []int // want "Consider preallocating x with capacity max\\(0, n-1\\)$"
 	for i := 1; i < n; i++ {
 		x = append(x, i)
 	}
@@ -124,7 +124,7 @@ func forIncVarNegativeOneToVarExclusive() {
 
 func forIncVarToMaxExclusive() {
 	m := 0
-	var x []int // want "Consider preallocating x with capacity 5 - m$"
+	var x []int // want "Consider preallocating x with capacity max\\(0, 5-m\\)$"
 	for i := m; i < 5; i++ {
 		x = append(x, i)
 	}
@@ -132,7 +132,7 @@ func forIncVarToMaxExclusive() {
 
 func forIncVarToMaxInclusive() {
 	m := 0
-	var x []int // want "Consider preallocating x with capacity 5 - m \\+ 1$"
+	var x []int // want "Consider preallocating x with capacity max\\(0, 5-m\\+1\\)$"
 	for i := m; i <= 5; i++ {
 		x = append(x, i)
 	}
@@ -140,7 +140,7 @@ func forIncVarToMaxInclusive() {
 
 func forIncVarToZeroExclusive() {
 	m := -5
-	var x []int // want "Consider preallocating x with capacity -m$"
+	var x []int // want "Consider preallocating x with capacity max\\(0, -m\\)$"
 	for i := m; i < 0; i++ {
 		x = append(x, i)
 	}
@@ -149,7 +149,7 @@ func forIncVarToZeroExclusive() {
 func forIncVarToVarExclusive() {
 	m := 0
 	n := 5
-	var x []int // want "Consider preallocating x with capacity n - m$"
+	var x []int // want "Consider preallocating x with capacity max\\(0, n-m\\)$"
 	for i := m; i < n; i++ {
 		x = append(x, i)
 	}
@@ -158,12 +158,27 @@ func forIncVarToVarExclusive() {
 func forIncVarToVarInclusive() {
 	m := 0
 	n := 5
-	var x []int // want "Consider preallocating x with capacity n - m \\+ 1$"
+	var x []int // want "Consider preallocating x with capacity max\\(0, n-m\\+1\\)$"
 	for i := m; i <= n; i++ {
 		x = append(x, i)
 	}
 }
 
+func forIncMaxToVarInclusive(version int) {
+	var x []int // want "Consider preallocating x with capacity max\\(0, version-4\\)$"
+	for i := 5; i <= version; i++ {
+		x = append(x, i)
+	}
+}
+
+func forIncVarToVarMaxShadowed(m, n int) {
+	max := n
+	var x []int // want "Consider preallocating x$"
+	for i := m; i < max; i++ {
+		x = append(x, i)
+	}
+}
+
 func forIterateZeroTimes() {
 	var x []int
 	for i := 0; i < 0; i++ {
@@ -0,0 +1,100 @@
+package test
+
+import "strings"
+
+// maps of slices that loops group elements into
+
+type groupsEvent struct {
+	User string
+	Tags []string
+}
+
+func groupsByUser(events []groupsEvent) map[string][]groupsEvent {
+	byUser := make(map[string][]groupsEvent) // want "Consider preallocating the slices grouped in byUser using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for _, e := range events {
+		byUser[e.User] = append(byUser[e.User], e)
+	}
+	return byUser
+}
+
+func groupsLiteral(ids []int) map[int][]int {
+	var byParity = map[int][]int{} // want "Consider preallocating the slices grouped in byParity using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for i := range ids {
+		byParity[i] = append(byParity[i], ids[i], -ids[i])
+	}
+	return byParity
+}
+
+func groupsSpread(events []groupsEvent) map[string][]string {
+	tags := make(map[string][]string, 8) // want "Consider preallocating the slices grouped in tags using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for _, e := range events {
+		tags[e.User] = append(tags[e.User], e.Tags...)
+	}
+	return tags
+}
+
+func groupsCounted(events []groupsEvent, counts int) map[string][]groupsEvent {
+	byUser := make(map[string][]groupsEvent) // want "Consider preallocating the slices grouped in byUser using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for _, e := range events {
+		byUser[e.User] = append(byUser[e.User], e)
+	}
+	_ = counts
+	return byUser
+}
+
+func groupsFiltered(events []groupsEvent) map[string][]groupsEvent {
+	byUser := make(map[string][]groupsEvent) // want "Consider preallocating the slices grouped in byUser using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for _, e := range events {
+		if e.User != "" {
+			byUser[e.User] = append(byUser[e.User], e)
+		}
+	}
+	return byUser
+}
+
+func groupsComputedKey(events []groupsEvent) map[string][]groupsEvent {
+	byUser := make(map[string][]groupsEvent) // want "Consider preallocating the slices grouped in byUser using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for _, e := range events {
+		byUser[strings.ToLower(e.User)] = append(byUser[strings.ToLower(e.User)], e)
+	}
+	return byUser
+}
+
+func groupsChannel(events chan groupsEvent) map[string][]groupsEvent {
+	byUser := make(map[string][]groupsEvent) // want "Consider preallocating the slices grouped in byUser using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for e := range events {
+		byUser[e.User] = append(byUser[e.User], e)
+	}
+	return byUser
+}
+
+func groupsExisting(events []groupsEvent, byUser map[string][]groupsEvent) {
+	for _, e := range events {
+		byUser[e.User] = append(byUser[e.User], e)
+	}
+}
+
+func groupsUsedBefore(events []groupsEvent, first groupsEvent) map[string][]groupsEvent {
+	byUser := make(map[string][]groupsEvent) // want "Consider preallocating byUser with size hint at most 1 \\+ len\\(events\\)$"
+	byUser[first.User] = []groupsEvent{first}
+	for _, e := range events {
+		byUser[e.User] = append(byUser[e.User], e)
+	}
+	return byUser
+}
+
+func groupsDifferentKeys(events []groupsEvent, other string) map[string][]groupsEvent {
+	byUser := make(map[string][]groupsEvent) // want "Consider preallocating byUser with size hint at most len\\(events\\)$"
+	for _, e := range events {
+		byUser[e.User] = append(byUser[other], e)
+	}
+	return byUser
+}
+
+func groupsIndices(events []groupsEvent) map[string][]int {
+	byUser := make(map[string][]int) // want "Consider preallocating the slices grouped in byUser using a counting pre-pass, or by partitioning a single backing slice by key$"
+	for i, e := range events {
+		byUser[e.User] = append(byUser[e.User], i)
+	}
+	return byUser
+}
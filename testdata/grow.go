@@ -0,0 +1,137 @@
+package test
+
+import "strconv"
+
+// slices the function did not make, which can only be grown before the loop
+
+func growParam(dst []string, src []int) []string {
+	for _, v := range src { // want "Consider growing dst by len\\(src\\) with slices.Grow before the loop$"
+		dst = append(dst, strconv.Itoa(v))
+	}
+	return dst
+}
+
+func growVariadic(a []int, rest ...int) []int {
+	for _, v := range a { // want "Consider growing rest by len\\(a\\) with slices.Grow before the loop$"
+		rest = append(rest, v)
+	}
+	return rest
+}
+
+func growFiltered(dst, src []int) []int {
+	for _, v := range src { // want "Consider growing dst by at most len\\(src\\) with slices.Grow before the loop$"
+		if v > 0 {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
+
+func growPadded(dst []byte, n, width int) []byte {
+	for i := n; i < width; i++ { // want "Consider growing dst by max\\(0, width-n\\) with slices.Grow before the loop$"
+		dst = append(dst, ' ')
+	}
+	return dst
+}
+
+func growAppendBefore(dst, src []int) []int {
+	dst = append(dst, 0)
+	for _, v := range src { // want "Consider growing dst by len\\(src\\) \\+ 1 with slices.Grow before the loop$"
+		dst = append(dst, v)
+	}
+	return append(dst, 1)
+}
+
+func growPointer(out *[]int, src []int) {
+	for _, v := range src { // want "Consider growing \\*out by len\\(src\\) with slices.Grow before the loop$"
+		*out = append(*out, v)
+	}
+}
+
+func growResliced(buf []byte, s string) []byte {
+	buf = buf[:0]
+	for i := range len(s) { // want "Consider growing buf by len\\(s\\) with slices.Grow before the loop$"
+		buf = append(buf, s[i])
+	}
+	return buf
+}
+
+func growReslicedMade(a [][]byte, use func([]byte)) {
+	buf := make([]byte, 0, 64)
+	for _, s := range a {
+		buf = buf[:0]
+		for _, c := range s {
+			buf = append(buf, c)
+		}
+		use(buf)
+	}
+}
+
+func growReslicedFilled(a [][]byte, n int, use func([]byte)) {
+	buf := make([]byte, n)
+	use(buf)
+	for _, s := range a {
+		buf = buf[:0]
+		for _, c := range s {
+			buf = append(buf, c)
+		}
+		use(buf)
+	}
+}
+
+func growReslicedEmpty(a [][]byte, use func([]byte)) {
+	var buf []byte
+	for _, s := range a {
+		buf = buf[:0]
+		for _, c := range s { // want "Consider growing buf by len\\(s\\) with slices.Grow before the loop$"
+			buf = append(buf, c)
+		}
+		use(buf)
+	}
+}
+
+func growCall(src []int, get func() []int) []int {
+	dst := get()
+	for _, v := range src { // want "Consider growing dst by len\\(src\\) with slices.Grow before the loop$"
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+func growShadowed(dst, src []int) []int {
+	slices := len(src)
+	for _, v := range src[:slices] { // want "Consider growing dst by len\\(src\\[:slices\\]\\) with slices.Grow before the loop$"
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+func growPassed(dst, src []int, use func([]int)) []int {
+	use(dst)
+	for _, v := range src {
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+func growPerIteration(dst []int, src [][]int) []int {
+	for _, s := range src {
+		dst = append(dst, s...)
+	}
+	return dst
+}
+
+func growUnknown(dst []int, ch chan int) []int {
+	for v := range ch {
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+func growFresh(src []int) []int {
+	dst := []int{} // want "Consider preallocating dst with capacity len\\(src\\)$"
+	for _, v := range src {
+		dst = append(dst, v)
+	}
+	return dst
+}
@@ -0,0 +1,121 @@
+package test
+
+// empty slices assigned by index within a loop
+
+func indexedMadeCap(a []int) []int {
+	x := make([]int, 0, len(a)) // want "x has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i, v := range a {
+		x[i] = v
+	}
+	return x
+}
+
+func indexedMadeEmpty(n int) []int {
+	var x = make([]int, 0) // want "x has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i := 0; i < n; i++ {
+		x[i] = i * i
+	}
+	return x
+}
+
+func indexedMadeLargerCap(a []int) []int {
+	x := make([]int, 0, 2*len(a)) // want "x has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i := range a {
+		x[i]++
+	}
+	return x
+}
+
+func indexedLiteral(a []string) []string {
+	x := []string{} // want "x has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i := range a {
+		x[i] = a[len(a)-1-i]
+	}
+	return x
+}
+
+func indexedZeroValue(a []int) []int {
+	var x []int // want "x has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i, v := range a {
+		x[i] = v
+	}
+	return x
+}
+
+func indexedOtherIndex(a []int) []int {
+	x := make([]int, 0, len(a)) // want "x has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i, v := range a {
+		x[len(a)-1-i] = v
+	}
+	return x
+}
+
+func indexedAfterAppend(a []int) []int {
+	x := make([]int, 0, len(a))
+	for i, v := range a {
+		x = append(x, 0)
+		x[i] = v
+	}
+	return x
+}
+
+func indexedAfterReslice(a []int) []int {
+	x := make([]int, 0, len(a))
+	for i, v := range a {
+		x = x[:i+1]
+		x[i] = v
+	}
+	return x
+}
+
+func indexedMadeLength(a []int) []int {
+	x := make([]int, len(a), len(a))
+	for i, v := range a {
+		x[i] = v
+	}
+	return x
+}
+
+func indexedResliced(a []int) []int {
+	x := make([]int, 0, len(a))
+	x = x[:len(a)]
+	for i, v := range a {
+		x[i] = v
+	}
+	return x
+}
+
+func indexedGuarded(a []int) []int {
+	x := make([]int, 0, len(a))
+	for i, v := range a {
+		if i < len(x) {
+			x[i] += v
+		} else {
+			x = append(x, v)
+		}
+	}
+	return x
+}
+
+func indexedGuardedSwitch(a []int) []int {
+	x := make([]int, 0, len(a))
+	for i, v := range a {
+		switch {
+		case i < len(x):
+			x[i] += v
+		default:
+			x = append(x, v)
+		}
+	}
+	return x
+}
+
+func indexedUnguardedCondition(a []int) []int {
+	x := make([]int, 0, len(a)) // want "x has length 0 when assigned by index in the loop, which panics with index out of range$"
+	for i, v := range a {
+		if v > 0 {
+			x[i] = v
+		}
+	}
+	return x
+}
@@ -0,0 +1,92 @@
+package test
+
+// labeled loops are analyzed like their unlabeled counterparts
+
+func labeledRange(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+outer:
+	for _, v := range a {
+		x = append(x, v)
+		if v > 0 {
+			continue outer
+		}
+	}
+}
+
+func labeledFor() {
+	var x []int // want "Consider preallocating x with capacity 10$"
+loop:
+	for i := 0; i < 10; i++ {
+		x = append(x, i)
+		if i > 5 {
+			continue loop
+		}
+	}
+}
+
+func labeledContinueFromInner(a [][]int) {
+	var x []int // want "Consider preallocating x with capacity at most 2 \\* len\\(a\\)$"
+outer:
+	for _, row := range a {
+		x = append(x, len(row))
+		for _, v := range row {
+			if v < 0 {
+				continue outer
+			}
+		}
+		x = append(x, 0)
+	}
+}
+
+func labeledBreakFromInner(a [][]int) {
+	var x []int
+outer:
+	for _, row := range a {
+		for _, v := range row {
+			if v < 0 {
+				break outer
+			}
+		}
+		x = append(x, len(row))
+	}
+}
+
+func labeledBreakSwitch(a []int) {
+	var x []int // want "Consider preallocating x with capacity at most 2 \\* len\\(a\\)$"
+	for _, v := range a {
+	sw:
+		switch v {
+		case 0:
+			if ok(v) {
+				break sw
+			}
+			x = append(x, v)
+		}
+		x = append(x, v)
+	}
+}
+
+func labeledBreakOuterFromSwitch(a []int) {
+	var x []int
+outer:
+	for _, v := range a {
+		switch v {
+		case 0:
+			break outer
+		}
+		x = append(x, v)
+	}
+}
+
+func labeledBreakInnerLoop(a [][]int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, row := range a {
+	inner:
+		for _, v := range row {
+			if v < 0 {
+				break inner
+			}
+		}
+		x = append(x, len(row))
+	}
+}
@@ -0,0 +1,125 @@
+package test
+
+// slices made with the length of the loop appending to them
+
+func madeLengthRange(a []int) []int {
+	x := make([]int, len(a)) // want "x is made with length len\\(a\\) and then appended to, so it starts with len\\(a\\) zero values$"
+	for i, v := range a {
+		x = append(x, v*i)
+	}
+	return x
+}
+
+func madeLengthFor(n int) []int {
+	var x = make([]int, n) // want "x is made with length n and then appended to, so it starts with n zero values$"
+	for i := 0; i < n; i++ {
+		x = append(x, i*i)
+	}
+	return x
+}
+
+func madeLengthRangeInt(n int) []string {
+	x := make([]string, n) // want "x is made with length n and then appended to, so it starts with n zero values$"
+	for i := range n {
+		x = append(x, "")
+		_ = i
+	}
+	return x
+}
+
+func madeLengthNoIndex(a []int) []int {
+	x := make([]int, len(a)) // want "x is made with length len\\(a\\) and then appended to, so it starts with len\\(a\\) zero values$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func madeLengthFiltered(a []int) []int {
+	x := make([]int, len(a)) // want "x is made with length len\\(a\\) and then appended to, so it starts with len\\(a\\) zero values$"
+	for i, v := range a {
+		if v > 0 {
+			x = append(x, i)
+		}
+	}
+	return x
+}
+
+func madeLengthString(s string) []byte {
+	x := make([]byte, len(s)) // want "x is made with length len\\(s\\) and then appended to, so it starts with len\\(s\\) zero values$"
+	for i := range s {
+		x = append(x, s[i])
+	}
+	return x
+}
+
+func madeLengthShadowed(a []int) []int {
+	x := make([]int, len(a)) // want "x is made with length len\\(a\\) and then appended to, so it starts with len\\(a\\) zero values$"
+	for i, v := range a {
+		i := v + i
+		x = append(x, i)
+	}
+	return x
+}
+
+func madeOtherLength(a []int) []int {
+	x := make([]int, 1) // want "Consider preallocating x with capacity 1 \\+ len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func madeLengthIndexed(a []int) []int {
+	x := make([]int, len(a))
+	for i, v := range a {
+		x[i] = v
+	}
+	return x
+}
+
+func madeLengthAppendedBefore(a []int) []int {
+	x := make([]int, len(a)) // want "Consider preallocating x with capacity 2\\*len\\(a\\) \\+ 1$"
+	x = append(x, -1)
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func madeLengthCopied(a []int) []int {
+	x := make([]int, len(a)) // want "Consider preallocating x with capacity 2 \\* len\\(a\\)$"
+	copy(x, a)
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func madeLengthFilledByLoop(a []int) []int {
+	x := make([]int, len(a)) // want "Consider preallocating x with capacity 2 \\* len\\(a\\)$"
+	for i := range a {
+		x[i] = a[i]
+	}
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func madeLengthAssigned(a []int) []int {
+	x := make([]int, len(a)) // want "Consider preallocating x with capacity 2 \\* len\\(a\\)$"
+	x[0] = 1
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func madeConstantLength() []byte {
+	x := make([]byte, 8) // want "Consider preallocating x with capacity 16$"
+	for i := 0; i < 8; i++ {
+		x = append(x, byte(i))
+	}
+	return x
+}
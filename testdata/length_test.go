@@ -0,0 +1,20 @@
+package test
+
+import "testing"
+
+func BenchmarkMadeLength(b *testing.B) {
+	n := b.N
+	x := make([]byte, n)
+	for i := 0; i < n; i++ {
+		x = append(x, byte(i))
+	}
+	_ = x
+}
+
+func madeLengthHelper(n int) []byte {
+	x := make([]byte, n) // want "x is made with length n and then appended to, so it starts with n zero values$"
+	for i := 0; i < n; i++ {
+		x = append(x, byte(i))
+	}
+	return x
+}
@@ -0,0 +1,135 @@
+package test
+
+// maps filled by loops, reported with -maps
+
+func mapRange(a []string) {
+	m := map[string]int{} // want "Consider preallocating m with size hint at most len\\(a\\)$"
+	for i, s := range a {
+		m[s] = i
+	}
+}
+
+func mapMake(a []string) {
+	m := make(map[string]bool) // want "Consider preallocating m with size hint at most len\\(a\\)$"
+	for _, s := range a {
+		m[s] = true
+	}
+}
+
+func mapVar(a []int) {
+	var m = map[int]int{} // want "Consider preallocating m with size hint at most len\\(a\\)$"
+	for _, n := range a {
+		m[n] = n
+	}
+}
+
+func mapIncremented(a []int) {
+	m := map[int]int{}
+	for _, n := range a {
+		m[n]++
+	}
+}
+
+func mapIndexed(a []string) {
+	m := map[int]string{} // want "Consider preallocating m with size hint len\\(a\\)$"
+	for i, s := range a {
+		m[i] = s
+	}
+}
+
+func mapCounted(a []string) {
+	m := map[string]int{}
+	for _, s := range a {
+		m[s] += len(s)
+	}
+}
+
+func mapFor(n int) {
+	m := map[int]int{} // want "Consider preallocating m with size hint n$"
+	for i := 0; i < n; i++ {
+		m[i] = i * i
+	}
+}
+
+func mapSeveralInserts(a []int) {
+	m := map[int]bool{} // want "Consider preallocating m with size hint at most 2 \\* len\\(a\\)$"
+	for _, n := range a {
+		m[n] = true
+		m[-n] = false
+	}
+}
+
+func mapNested(a [][]int) {
+	m := map[int]int{} // want "Consider preallocating m with size hint at most 3 \\* len\\(a\\)$"
+	for _, row := range a {
+		for j := 0; j < 3; j++ {
+			m[row[j]] = j
+		}
+	}
+}
+
+func mapFiltered(a []int) {
+	m := map[int]struct{}{} // want "Consider preallocating m with size hint at most len\\(a\\)$"
+	for _, n := range a {
+		if n > 0 {
+			m[n] = struct{}{}
+		}
+	}
+}
+
+func mapInsertedBefore(a []string) {
+	m := map[string]int{} // want "Consider preallocating m with size hint at most 1 \\+ len\\(a\\)$"
+	m[""] = -1
+	for i, s := range a {
+		m[s] = i
+	}
+}
+
+func mapFromMap(src map[string]int) {
+	m := map[int]string{} // want "Consider preallocating m with size hint at most len\\(src\\)$"
+	for k, v := range src {
+		m[v] = k
+	}
+}
+
+func mapNotEmpty(a []string) {
+	m := map[string]int{"": -1} // want "Consider preallocating m with size hint at most 1 \\+ len\\(a\\)$"
+	for i, s := range a {
+		m[s] = i
+	}
+}
+
+func mapHinted(a []string) {
+	m := make(map[string]int, len(a))
+	for i, s := range a {
+		m[s] = i
+	}
+}
+
+func mapOnlyRead(a []string, m map[string]int) {
+	seen := map[string]bool{}
+	for _, s := range a {
+		if seen[s] {
+			m[s]++
+		}
+	}
+}
+
+func mapReassigned(a []string, other map[string]int) {
+	m := map[string]int{}
+	m = other
+	for i, s := range a {
+		m[s] = i
+	}
+}
+
+func mapNil(a []string) map[string]int {
+	var m map[string]int
+	for i, s := range a {
+		if m == nil {
+			m = map[string]int{}
+		}
+		m[s] = i
+	}
+	return m
+}
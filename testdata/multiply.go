@@ -0,0 +1,143 @@
+package test
+
+// appends within nested loops multiply the capacity by each loop count
+
+func multiplyRangeInts(n, m int) {
+	var grid []int // want "Consider preallocating grid with capacity n \\* m$"
+	for i := range n {
+		for j := range m {
+			grid = append(grid, i*j)
+		}
+	}
+}
+
+func multiplyRangeSlices(a, b []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) \\* len\\(b\\)$"
+	for _, i := range a {
+		for _, j := range b {
+			x = append(x, i*j)
+		}
+	}
+}
+
+func multiplyConstants() {
+	var x []int // want "Consider preallocating x with capacity 12$"
+	for i := range 3 {
+		for j := range 4 {
+			x = append(x, i*j)
+		}
+	}
+}
+
+func multiplyInnerConstant(a []int) {
+	var x []int // want "Consider preallocating x with capacity 2 \\* len\\(a\\)$"
+	for _, v := range a {
+		for range 2 {
+			x = append(x, v)
+		}
+	}
+}
+
+func multiplyThreeLevels(n, m, k int) {
+	var x []int // want "Consider preallocating x with capacity n \\* m \\* k$"
+	for i := range n {
+		for j := range m {
+			for l := range k {
+				x = append(x, i*j*l)
+			}
+		}
+	}
+}
+
+func multiplyOuterAndInner(a, b []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) \\+ len\\(a\\)\\*len\\(b\\)$"
+	for _, i := range a {
+		x = append(x, i)
+		for _, j := range b {
+			x = append(x, j)
+		}
+	}
+}
+
+func multiplyForLoops(n, m int) {
+	var x []int // want "Consider preallocating x with capacity n \\* m$"
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			x = append(x, i*j)
+		}
+	}
+}
+
+func multiplyConditionalInner(a, b []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\) \\* len\\(b\\)$"
+	for _, i := range a {
+		if ok(i) {
+			for _, j := range b {
+				x = append(x, j)
+			}
+		}
+	}
+}
+
+func multiplyInnerBreak(a, b []int) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\) \\* len\\(b\\)$"
+	for _, i := range a {
+		for _, j := range b {
+			if j > i {
+				break
+			}
+			x = append(x, j)
+		}
+	}
+}
+
+func multiplyInnerZero(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, i := range a {
+		x = append(x, i)
+		for j := range 0 {
+			x = append(x, j)
+		}
+	}
+}
+
+func multiplyInnerChan(a []int, ch chan int) {
+	var x []int
+	for range a {
+		for j := range ch {
+			x = append(x, j)
+		}
+	}
+}
+
+type row struct {
+	cells []int
+}
+
+func sumOverElements(rows []row) {
+	var x []int // want "Consider preallocating x using a counting pre-pass, as each iteration appends len\\(row.cells\\) elements$"
+	for _, row := range rows {
+		for _, c := range row.cells {
+			x = append(x, c)
+		}
+	}
+}
+
+func sumOverLocal(a []int) {
+	var x []int // want "Consider preallocating x using a counting pre-pass, as each iteration appends k elements$"
+	for _, v := range a {
+		k := v % 3
+		for i := range k {
+			x = append(x, i)
+		}
+	}
+}
+
+func sumTriangular(n int) {
+	var x []int // want "Consider preallocating x using a counting pre-pass, as each iteration appends i elements$"
+	for i := range n {
+		for j := range i {
+			x = append(x, j)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package test
+
+// loops whose count is changed by their own body
+
+func mutatedLoopVariable(n int) {
+	var x []int
+	for i := 0; i < n; i++ {
+		x = append(x, i)
+		i++
+	}
+}
+
+func mutatedLoopVariableAssign(a []int) {
+	var x []int
+	for i := 0; i < len(a); i++ {
+		x = append(x, a[i])
+		if a[i] == 0 {
+			i = len(a)
+		}
+	}
+}
+
+func mutatedLoopVariableAddress(n int) {
+	var x []int
+	for i := 0; i < n; i++ {
+		x = append(x, i)
+		skip(&i)
+	}
+}
+
+func mutatedBound(n int) {
+	var x []int
+	for i := 0; i < n; i++ {
+		x = append(x, i)
+		n--
+	}
+}
+
+func mutatedBoundSlice(a []int) {
+	var x []int
+	for i := 0; i < len(a); i++ {
+		x = append(x, a[i])
+		a = a[1:]
+	}
+}
+
+func mutatedBoundSelfAppend() {
+	x := []int{1}
+	for i := 0; i < len(x); i++ {
+		x = append(x, i)
+	}
+}
+
+func mutatedBoundField(s struct{ n int }) {
+	var x []int
+	for i := 0; i < s.n; i++ {
+		x = append(x, i)
+		s.n++
+	}
+}
+
+func mutatedBoundMap(m map[int]int) {
+	var x []int
+	for i := 0; i < len(m); i++ {
+		x = append(x, i)
+		m[i+len(m)] = i
+	}
+}
+
+func mutatedBoundPointer(p *[]int) {
+	var x []int
+	for i := 0; i < len(*p); i++ {
+		x = append(x, (*p)[i])
+		*p = (*p)[1:]
+	}
+}
+
+func mutatedBoundArrayElement(a [4]int) {
+	var x []int
+	for i := 0; i < a[0]; i++ {
+		x = append(x, i)
+		a[0]--
+	}
+}
+
+func mutatedBoundPointerArrayElement(p *[4]int) {
+	var x []int
+	for i := 0; i < p[0]; i++ {
+		x = append(x, i)
+		p[0]--
+	}
+}
+
+func mutatedWhileBound(n int) {
+	var x []int
+	i := 0
+	for i < n {
+		x = append(x, i)
+		n--
+		i++
+	}
+}
+
+func mutatedNestedBound(a []int, n int) {
+	var x []int
+	for range a {
+		for j := 0; j < n; j++ {
+			x = append(x, j)
+			n--
+		}
+	}
+}
+
+func mutatedRangeMapInsert(m map[int]int) {
+	var x []int
+	for k, v := range m {
+		x = append(x, v)
+		m[k+1] = v
+	}
+}
+
+func mutatedRangeMapDelete(m map[int]int) {
+	var x []int
+	for k, v := range m {
+		x = append(x, v)
+		delete(m, k)
+	}
+}
+
+func mutatedRangeMapClear(m map[int]int) {
+	var x []int
+	for _, v := range m {
+		x = append(x, v)
+		clear(m)
+	}
+}
+
+type mutatedCounter struct{ n int }
+
+func (c *mutatedCounter) grow() { c.n++ }
+
+func (c mutatedCounter) size() int { return c.n }
+
+func mutatedBoundMethod(s mutatedCounter) {
+	var x []int
+	for i := 0; i < s.n; i++ {
+		x = append(x, i)
+		s.grow()
+	}
+}
+
+func mutatedBoundPointerArg(s mutatedCounter) {
+	var x []int
+	for i := 0; i < s.n; i++ {
+		x = append(x, i)
+		mutatedGrow(&s)
+	}
+}
+
+func mutatedGrow(c *mutatedCounter) { c.n++ }
+
+func unmutatedBoundValueMethod(s mutatedCounter) {
+	var x []int // want "Consider preallocating x with capacity s.n$"
+	for i := 0; i < s.n; i++ {
+		x = append(x, i+s.size())
+	}
+}
+
+func mutatedRangeMapHelper(m map[int]int) {
+	var x []int
+	for k, v := range m {
+		x = append(x, v)
+		mutatedInsert(m, k+1)
+	}
+}
+
+func mutatedInsert(m map[int]int, k int) { m[k] = k }
+
+// the range expression of a slice is evaluated once, so changing it has no effect
+func mutatedRangeSlice(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+		a = nil
+	}
+}
+
+func unmutatedBoundElement(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for i := 0; i < len(a); i++ {
+		x = append(x, a[i])
+		a[i] = 0
+	}
+}
+
+func unmutatedBoundShadowed(n int) {
+	var x []int // want "Consider preallocating x with capacity n$"
+	for i := 0; i < n; i++ {
+		n := i * 2
+		x = append(x, n)
+	}
+}
+
+func unmutatedRangeMapOther(m, o map[int]int) {
+	var x []int // want "Consider preallocating x with capacity len\\(m\\)$"
+	for k, v := range m {
+		x = append(x, v)
+		o[k] = v
+		delete(o, v)
+	}
+}
+
+func skip(*int) {}
+
+func unmutatedNoCondition(a []int) {
+	var x []int
+	for range a {
+		for i := 0; ; i++ {
+			if i > 3 {
+				break
+			}
+			x = append(x, i)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// uses after the loop telling a nil slice from an empty one
+
+func nilsCompared(a []int) bool {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) only if non-empty, as it is compared to nil$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x == nil
+}
+
+func nilsComparedFiltered(a []int) bool {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\), although it is compared to nil and is no longer nil if empty$"
+	for _, v := range a {
+		if v > 0 {
+			x = append(x, v)
+		}
+	}
+	return x != nil
+}
+
+func nilsComparedBefore(a []int) bool {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	empty := x == nil
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return empty
+}
+
+func nilsMarshalled(a []int) ([]byte, error) {
+	x := []string(nil) // want "Consider preallocating x with capacity len\\(a\\) only if non-empty, as it is marshalled, where a nil slice encodes as null$"
+	for _, v := range a {
+		x = append(x, strconv.Itoa(v))
+	}
+	return json.Marshal(x)
+}
+
+type nilsPage struct {
+	Items []int `json:"items"`
+}
+
+func nilsMarshalledField(a []int) ([]byte, error) {
+	var page nilsPage // want "Consider preallocating page.Items with capacity len\\(a\\) by making it before the loop only if non-empty, as it is marshalled, where a nil slice encodes as null$"
+	for _, v := range a {
+		page.Items = append(page.Items, v)
+	}
+	return json.MarshalIndent(&page, "", "\t")
+}
+
+func nilsEncodedLiteral(w io.Writer, a []int) error {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) only if non-empty, as it is marshalled, where a nil slice encodes as null$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return json.NewEncoder(w).Encode(nilsPage{Items: x})
+}
+
+func nilsMarshalledEmpty(a []int) ([]byte, error) {
+	x := []int{} // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return json.Marshal(x)
+}
+
+func NilsExported(a []int) []int {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) only if non-empty, as it is returned from an exported function$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func NilsExportedResult(a []int) (out []int) { // want "Consider preallocating out with capacity len\\(a\\) only if non-empty, as it is returned from an exported function$"
+	for _, v := range a {
+		out = append(out, v)
+	}
+	return
+}
+
+func NilsExportedMoved(a []int) []int {
+	var x []int // want "Consider preallocating x with capacity n by making it before the loop only if non-empty, as it is returned from an exported function$"
+	n := len(a) / 2
+	for i := range n {
+		x = append(x, a[i])
+	}
+	return x
+}
+
+func NilsExportedConstant() []int {
+	var x []int // want "Consider preallocating x with capacity 3$"
+	for i := range 3 {
+		x = append(x, i)
+	}
+	return x
+}
+
+func NilsExportedGrow(dst, src []int) []int {
+	for _, v := range src { // want "Consider growing dst by at most len\\(src\\) with slices.Grow before the loop, although it is returned from an exported function and is no longer nil if empty$"
+		if v > 0 {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
+
+func NilsExportedGrowExact(dst, src []int) []int {
+	for _, v := range src { // want "Consider growing dst by len\\(src\\) with slices.Grow before the loop$"
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+func NilsExportedClosure(a []int) func() []int {
+	return func() []int {
+		var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+		for _, v := range a {
+			x = append(x, v)
+		}
+		return x
+	}
+}
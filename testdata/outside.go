@@ -0,0 +1,109 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+)
+
+// appends made outside of the loops appending to a slice
+
+func outsideHeaderTrailer(rows []string) []string {
+	var lines []string // want "Consider preallocating lines with capacity 2 \\+ len\\(rows\\)$"
+	lines = append(lines, "header")
+	for _, row := range rows {
+		lines = append(lines, row)
+	}
+	lines = append(lines, "trailer")
+	return lines
+}
+
+func outsideTrailerOnly(rows []string) []string {
+	var lines []string // want "Consider preallocating lines with capacity len\\(rows\\) \\+ 2$"
+	for _, row := range rows {
+		lines = append(lines, row)
+	}
+	lines = append(lines, "total", "end")
+	return lines
+}
+
+func outsideBetweenLoops(a, b []int) []int {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) \\+ 1 \\+ len\\(b\\) \\+ 1$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	x = append(x, -1)
+	for _, v := range b {
+		x = append(x, v)
+	}
+	x = append(x, -1)
+	return x
+}
+
+func outsideTrailerSpread(a, b []int) []int {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\) \\+ len\\(b\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	x = append(x, b...)
+	return x
+}
+
+func outsideTrailerConditional(a []int, cond bool) []int {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\) \\+ 1$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	if cond {
+		x = append(x, 0)
+	}
+	return x
+}
+
+func outsideTrailerAfterUse(a []int) []int {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	fmt.Println(x)
+	x = append(x, 0)
+	return x
+}
+
+func outsideTrailerAfterReassign(a, b []int) []int {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	fmt.Println(x)
+	x = b
+	x = append(x, 0)
+	return x
+}
+
+func outsideTrailerUnknown(a []int, next func() []int) []int {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	x = append(x, next()...)
+	x = append(x, 0)
+	return x
+}
+
+func outsideTrailerMap(a []string) map[string]bool {
+	m := make(map[string]bool) // want "Consider preallocating m with size hint at most len\\(a\\) \\+ 1$"
+	for _, k := range a {
+		m[k] = true
+	}
+	m[""] = false
+	return m
+}
+
+func outsideTrailerBuilder(a []byte) string {
+	var sb strings.Builder // want "Consider preallocating sb with capacity len\\(a\\) \\+ 1 by growing it before the loop$"
+	for _, b := range a {
+		sb.WriteByte(b)
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
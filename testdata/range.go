@@ -100,7 +100,36 @@ func rangeIntTypeConvert() {
 }
 
 func rangeMultiple() {
-	var x []int // want "Consider preallocating x with capacity 5 \\+ n \\+ len\\(s\\) \\+ \\(n - m \\+ 1\\)$"
+	n := 5
+	s := "Hello"
+	m := 0
+	var x []int // want "Consider preallocating x with capacity 5 \\+ n \\+ len\\(s\\) \\+ max\\(0, n-m\\+1\\)$"
+	for i := range 5 {
+		x = append(x, i)
+	}
+	for i := range n {
+		x = append(x, i)
+	}
+	for i := range s {
+		x = append(x, i)
+	}
+	for i := m; i <= n; i++ {
+		x = append(x, i)
+	}
+}
+
+func rangeMultipleSame(a []int) {
+	var x []int // want "Consider preallocating x with capacity 2 \\* len\\(a\\)$"
+	for _, v := range a {
+		x = append(x, v)
+	}
+	for _, v := range a {
+		x = append(x, -v)
+	}
+}
+
+func rangeMultipleOutOfScope() {
+	var x []int // want "Consider preallocating x$"
 	for i := range 5 {
 		x = append(x, i)
 	}
@@ -123,7 +152,18 @@ func rangeMultipleWithPartialUnresolvedCapacity() {
 	for i := range 5 {
 		x = append(x, i)
 	}
+	for i := 1; i < 100; i = next(i) {
+		x = append(x, i)
+	}
+}
+
+func next(i int) int {
+	return i * 3
+}
+
+func rangeExternalTypeDef() {
 	var s sort.IntSlice
+	var x []int // want "Consider preallocating x with capacity len\\(s\\)$"
 	for i := range s {
 		x = append(x, i)
 	}
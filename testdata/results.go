@@ -0,0 +1,89 @@
+package test
+
+import "strconv"
+
+// named results, which start out as nil slices
+
+func resultsAppend(a []int) (out []string) { // want "Consider preallocating out with capacity len\\(a\\)$"
+	for _, v := range a {
+		out = append(out, strconv.Itoa(v))
+	}
+	return out
+}
+
+func resultsBare(a []int) (out []int, err error) { // want "Consider preallocating out with capacity len\\(a\\)$"
+	for _, v := range a {
+		out = append(out, v)
+	}
+	return
+}
+
+func resultsTail(a []int) (out []int) { // want "Consider preallocating out with capacity len\\(a\\) \\+ 1$"
+	for _, v := range a {
+		out = append(out, v)
+	}
+	return append(out, 0)
+}
+
+func resultsTailBranch(a []int, done bool) (out []int) { // want "Consider preallocating out with capacity at most len\\(a\\) \\+ 3$"
+	for _, v := range a {
+		out = append(out, v)
+	}
+	if done {
+		return append(out, 0)
+	}
+	return append(out, 1, 2)
+}
+
+func resultsLocal(a []int) (out []int) { // want "Consider preallocating out with capacity n by making it before the loop$"
+	n := len(a) / 2
+	for i := range n {
+		out = append(out, a[i])
+	}
+	return out
+}
+
+func resultsGrouped(a []int) (evens, odds []int) { // want "Consider preallocating evens with capacity at most len\\(a\\)$" "Consider preallocating odds with capacity at most len\\(a\\)$"
+	for _, v := range a {
+		if v%2 == 0 {
+			evens = append(evens, v)
+		} else {
+			odds = append(odds, v)
+		}
+	}
+	return
+}
+
+func resultsClosure(a []int) func() []int {
+	return func() (out []int) { // want "Consider preallocating out with capacity len\\(a\\)$"
+		for _, v := range a {
+			out = append(out, v)
+		}
+		return
+	}
+}
+
+func resultsReassigned(a, b []int) (out []int) {
+	out = b
+	for _, v := range a { // want "Consider growing out by len\\(a\\) with slices.Grow before the loop$"
+		out = append(out, v)
+	}
+	return out
+}
+
+func resultsDeferred(a []int) (out []int) {
+	defer func() {
+		out = append(out, 0)
+	}()
+	for _, v := range a {
+		out = append(out, v)
+	}
+	return out
+}
+
+func resultsNotSlice(a []int) (n int) {
+	for range a {
+		n++
+	}
+	return n
+}
@@ -101,3 +101,28 @@ func multipleVarNames() {
 		y = append(y, i)
 	}
 }
+
+func multipleVarNamesPartiallyEligible() {
+	var x, y, z []int // want "Consider preallocating y with capacity 5$"
+	for i := range 5 {
+		y = append(y, i)
+	}
+	_, _ = x, z
+}
+
+func multipleVarSpecs() {
+	var ( // want "Consider preallocating x with capacity 5$"
+		x []int
+		n int
+	)
+	for i := range 5 {
+		x = append(x, i+n)
+	}
+}
+
+func multipleAssignNames() {
+	x, n := []int{}, 0 // want "Consider preallocating x with capacity 5$"
+	for i := range 5 {
+		x = append(x, i+n)
+	}
+}
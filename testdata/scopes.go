@@ -0,0 +1,89 @@
+package test
+
+// declarations are tracked across nested blocks by identity rather than name
+
+func scopeNestedIf(a []int, cond bool) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\)$"
+	if cond {
+		for _, v := range a {
+			x = append(x, v)
+		}
+	}
+}
+
+func scopeNestedElse(a, b []int, cond bool) {
+	var x []int // want "Consider preallocating x with capacity at most len\\(a\\) \\+ len\\(b\\)$"
+	if cond {
+		for _, v := range a {
+			x = append(x, v)
+		}
+	} else {
+		for _, v := range b {
+			x = append(x, v)
+		}
+	}
+}
+
+func scopeNestedBlock(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	{
+		for _, v := range a {
+			x = append(x, v)
+		}
+	}
+}
+
+func scopeShadowed(a []int, cond bool) {
+	var x []int
+	if cond {
+		var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+		for _, v := range a {
+			x = append(x, v)
+		}
+		_ = x
+	}
+	_ = x
+}
+
+func scopeShadowedInLoop(a [][]int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	for _, row := range a {
+		var x []int // want "Consider preallocating x with capacity len\\(row\\)$"
+		for _, v := range row {
+			x = append(x, v)
+		}
+		_ = x
+	}
+	for _, row := range a {
+		x = append(x, len(row))
+	}
+}
+
+func scopeLoopBody(a [][]int) {
+	var x []int
+	for len(a) > 0 {
+		for _, v := range a[0] {
+			x = append(x, v)
+		}
+		a = a[1:]
+	}
+	for _, v := range a {
+		x = append(x, len(v))
+	}
+}
+
+func scopeClosure(a []int) {
+	var x []int
+	func() {
+		for _, v := range a {
+			x = append(x, v)
+		}
+	}()
+	f := func() {
+		var y []int // want "Consider preallocating y with capacity len\\(a\\)$"
+		for _, v := range a {
+			y = append(y, v)
+		}
+	}
+	f()
+}
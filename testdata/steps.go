@@ -0,0 +1,115 @@
+package test
+
+// for loops stepping by other than one
+
+func stepAdd(n int) {
+	var x []int // want "Consider preallocating x with capacity \\(n \\+ 1\\) / 2$"
+	for i := 0; i < n; i += 2 {
+		x = append(x, i)
+	}
+}
+
+func stepAddInclusive(n int) {
+	var x []int // want "Consider preallocating x with capacity \\(n \\+ 3\\) / 3$"
+	for i := 0; i <= n; i += 3 {
+		x = append(x, i)
+	}
+}
+
+func stepAddConst() {
+	var x []int // want "Consider preallocating x with capacity 3$"
+	for i := 1; i < 10; i += 3 {
+		x = append(x, i)
+	}
+}
+
+func stepChunk(buf []byte, chunk int) {
+	var x [][]byte // want "Consider preallocating x with capacity \\(len\\(buf\\) \\+ max\\(chunk, 1\\) - 1\\) / max\\(chunk, 1\\)$"
+	for i := 0; i < len(buf); i += chunk {
+		x = append(x, buf[i:min(i+chunk, len(buf))])
+	}
+}
+
+func stepSubVar(n, k int) {
+	var x []int // want "Consider preallocating x with capacity \\(n \\+ max\\(k, 1\\) - 1\\) / max\\(k, 1\\)$"
+	for i := n; i > 0; i -= k {
+		x = append(x, i)
+	}
+}
+
+func stepVarMaxShadowed(n, k int, max func(...int) int) {
+	var x []int // want "Consider preallocating x$"
+	for i := 0; i < n; i += k {
+		x = append(x, max(i, 1))
+	}
+}
+
+func stepSub(n int) {
+	var x []int // want "Consider preallocating x with capacity \\(n \\+ 1\\) / 2$"
+	for i := n; i > 0; i -= 2 {
+		x = append(x, i)
+	}
+}
+
+func stepNegative(n int) {
+	var x []int // want "Consider preallocating x with capacity \\(n \\+ 1\\) / 2$"
+	for i := n; i > 0; i += -2 {
+		x = append(x, i)
+	}
+}
+
+func stepNegativeWrongWay(n int) {
+	var x []int
+	for i := 0; i < n; i -= 2 {
+		x = append(x, i)
+	}
+}
+
+func stepNotEqual(n int) {
+	var x []int // want "Consider preallocating x$"
+	for i := 0; i != n; i += 2 {
+		x = append(x, i)
+	}
+}
+
+func stepMulConst() {
+	var x []int // want "Consider preallocating x with capacity 7$"
+	for i := 1; i < 100; i *= 2 {
+		x = append(x, i)
+	}
+}
+
+func stepShiftConst() {
+	var x []int // want "Consider preallocating x with capacity 5$"
+	for i := 1; i <= 256; i <<= 2 {
+		x = append(x, i)
+	}
+}
+
+func stepMul(n int) {
+	var x []int // want "Consider preallocating x with capacity at most bits.Len\\(uint\\(n\\)\\)$"
+	for i := 1; i < n; i *= 2 {
+		x = append(x, i)
+	}
+}
+
+func stepShift(n int) {
+	var x []int // want "Consider preallocating x with capacity at most \\(bits.Len\\(uint\\(n\\)\\) \\+ 1\\) / 2$"
+	for i := 1; i < n; i <<= 2 {
+		x = append(x, i)
+	}
+}
+
+func stepDiv(n int) {
+	var x []int // want "Consider preallocating x with capacity at most bits.Len\\(uint\\(n\\)\\)$"
+	for i := n; i > 0; i /= 2 {
+		x = append(x, i)
+	}
+}
+
+func stepMulFromZero(n int) {
+	var x []int // want "Consider preallocating x$"
+	for i := 0; i < n; i *= 2 {
+		x = append(x, i)
+	}
+}
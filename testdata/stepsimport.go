@@ -0,0 +1,12 @@
+package test
+
+import (
+	"fmt"
+)
+
+func stepMulImported(n int) {
+	var x []string // want "Consider preallocating x with capacity at most bits.Len\\(uint\\(n\\)\\)$"
+	for i := 1; i <= n; i *= 2 {
+		x = append(x, fmt.Sprint(i))
+	}
+}
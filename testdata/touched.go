@@ -0,0 +1,146 @@
+package test
+
+import "fmt"
+
+// uses of a slice between its declaration and the loop appending to it
+
+func touchedAppendBefore(a []int) {
+	var x []int // want "Consider preallocating x with capacity 1 \\+ len\\(a\\)$"
+	x = append(x, 0)
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func touchedAppendSpreadBefore(a, b []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(b\\) \\+ len\\(a\\)$"
+	x = append(x, b...)
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func touchedAppendStringBefore(a []byte) {
+	var x []byte // want "Consider preallocating x with capacity 5 \\+ len\\(a\\)$"
+	x = append(x, "hello"...)
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func touchedAppendConditional(a []int, cond bool) {
+	var x []int // want "Consider preallocating x with capacity at most 2 \\+ len\\(a\\)$"
+	if cond {
+		x = append(x, 1, 2)
+	}
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func touchedAppendUnknown(a []int, ch chan []int) {
+	var x []int
+	x = append(x, <-ch...)
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func touchedReassigned(a, b []int) {
+	var x []int
+	x = b
+	for _, v := range a { // want "Consider growing x by len\\(a\\) with slices.Grow before the loop$"
+		x = append(x, v)
+	}
+}
+
+func touchedAddressTaken(a []int) {
+	var x []int
+	p := &x
+	for _, v := range a {
+		x = append(x, v)
+	}
+	_ = p
+}
+
+func touchedPassed(a []int) {
+	var x []int
+	fmt.Println(x)
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func touchedAliased(a []int) {
+	var x []int
+	y := x
+	for _, v := range a {
+		x = append(x, v)
+	}
+	_ = y
+}
+
+func touchedResliced(a []int) {
+	var x []int
+	for _, v := range a {
+		x = append(x, v)
+		if len(x) > 10 {
+			x = x[1:]
+		}
+	}
+}
+
+func touchedBetweenLoops(a, b []int) {
+	var x []int
+	for _, v := range a {
+		x = append(x, v)
+	}
+	fmt.Println(x)
+	for _, v := range b {
+		x = append(x, v)
+	}
+}
+
+func touchedClosure(a []int) {
+	var x []int
+	add := func(v int) { x = append(x, v) }
+	add(0)
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
+
+func touchedReadsOnly(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	if x == nil && len(x) == 0 {
+		fmt.Println(cap(x))
+	}
+	for _, v := range a {
+		x = append(x, v)
+		fmt.Println(x[len(x)-1])
+	}
+	fmt.Println(x)
+}
+
+func touchedReturnBefore(a []int) []int {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	if len(a) == 0 {
+		return x
+	}
+	for _, v := range a {
+		x = append(x, v)
+	}
+	return x
+}
+
+func touchedShadowed(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	{
+		x := []int{1}
+		x = append(x, 2)
+		fmt.Println(x)
+	}
+	for _, v := range a {
+		x = append(x, v)
+	}
+}
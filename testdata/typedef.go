@@ -1,9 +1,16 @@
 package test
 
-import . "sort"
+import (
+	"sort"
+	. "sort"
+)
 
 type ints []int
 
+type intsAlias = []int
+
+type list[T any] []T
+
 func assignTypeDefEmptyLit() {
 	x := ints{} // want "Consider preallocating x with capacity 5$"
 	for i := range "Hello" {
@@ -55,7 +62,42 @@ func inlineTypeDefEmptyLit() {
 }
 
 func externalTypeDefEmptyLit() {
-	var x IntSlice
+	var x IntSlice // want "Consider preallocating x with capacity 5$"
+	for i := range "Hello" {
+		x = append(x, i)
+	}
+}
+
+func assignAliasEmptyLit() {
+	x := intsAlias{} // want "Consider preallocating x with capacity 5$"
+	for i := range "Hello" {
+		x = append(x, i)
+	}
+}
+
+func varAlias() {
+	var x intsAlias // want "Consider preallocating x with capacity 5$"
+	for i := range "Hello" {
+		x = append(x, i)
+	}
+}
+
+func assignExternalTypeDefEmptyMake() {
+	x := make(sort.IntSlice, 0) // want "Consider preallocating x with capacity 5$"
+	for i := range "Hello" {
+		x = append(x, i)
+	}
+}
+
+func assignGenericTypeDefEmptyMake() {
+	x := make(list[int], 0) // want "Consider preallocating x with capacity 5$"
+	for i := range "Hello" {
+		x = append(x, i)
+	}
+}
+
+func varGenericTypeDef() {
+	var x list[int] // want "Consider preallocating x with capacity 5$"
 	for i := range "Hello" {
 		x = append(x, i)
 	}
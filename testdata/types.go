@@ -0,0 +1,103 @@
+package test
+
+import (
+	"archive/tar"
+	"image"
+	. "net/url"
+)
+
+func rangeDotImport() {
+	var v Values
+	var x []string // want "Consider preallocating x with capacity len\\(v\\)$"
+	for k := range v {
+		x = append(x, k)
+	}
+}
+
+func rangeImportedStructField(hdr tar.Header) {
+	var x []string // want "Consider preallocating x with capacity len\\(hdr.PAXRecords\\)$"
+	for k := range hdr.PAXRecords {
+		x = append(x, k)
+	}
+}
+
+func rangeImportedStructFieldPointer(img *image.RGBA) {
+	var x []byte // want "Consider preallocating x with capacity len\\(img.Pix\\)$"
+	for _, b := range img.Pix {
+		x = append(x, b)
+	}
+}
+
+func rangeMethodResult(img *image.RGBA) {
+	var x []int // want "Consider preallocating x$"
+	for i := range img.Bounds().Dx() {
+		x = append(x, i)
+	}
+}
+
+type matrix struct {
+	rows map[string][]int
+}
+
+func (m *matrix) byName() map[string][]int {
+	return m.rows
+}
+
+func rangeLocalMethodResult(m *matrix) {
+	var x []string // want "Consider preallocating x$"
+	for k := range m.byName() {
+		x = append(x, k)
+	}
+}
+
+func rangeTypeParam[S ~[]E, E any](s S) {
+	var x []E // want "Consider preallocating x with capacity len\\(s\\)$"
+	for _, e := range s {
+		x = append(x, e)
+	}
+}
+
+func rangeTypeParamInt[N ~int](n N) {
+	var x []N // want "Consider preallocating x with capacity n$"
+	for i := range n {
+		x = append(x, i)
+	}
+}
+
+func declTypeParam[S ~[]int]() {
+	var x S // want "Consider preallocating x with capacity 5$"
+	for i := range 5 {
+		x = append(x, i)
+	}
+}
+
+func makeChan() {
+	x := make(chan int, 0)
+	for i := range 5 {
+		x <- i
+	}
+}
+
+func shadowedMake() {
+	make := func(s []int, n int) []int { return s[:n] }
+	x := make(nil, 0)
+	for i := range 5 { // want "Consider growing x by 5 with slices.Grow before the loop$"
+		x = append(x, i)
+	}
+}
+
+func shadowedAppend() {
+	append := func(s []int, n int) []int { return s }
+	var x []int
+	for i := range 5 {
+		x = append(x, i)
+	}
+}
+
+func shadowedNil() {
+	nil := []int{}
+	x := []int(nil)
+	for i := range 5 { // want "Consider growing x by 5 with slices.Grow before the loop$"
+		x = append(x, i)
+	}
+}
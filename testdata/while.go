@@ -0,0 +1,117 @@
+package test
+
+// loops with only a condition, driven by a counter declared just before them
+
+func whileIncrementLast(a []int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	i := 0
+	for i < len(a) {
+		x = append(x, a[i])
+		i++
+	}
+}
+
+func whileIncrementFirst(n int) {
+	var x []int // want "Consider preallocating x with capacity n$"
+	var i = 0
+	for i < n {
+		i++
+		x = append(x, i)
+	}
+}
+
+func whileStep(s string) {
+	var x []byte // want "Consider preallocating x with capacity \\(len\\(s\\) \\+ 1\\) / 2$"
+	i := 0
+	for i < len(s) {
+		x = append(x, s[i])
+		i += 2
+	}
+}
+
+func whileDecrement(n int) {
+	var x []int // want "Consider preallocating x with capacity n$"
+	i := n
+	for i > 0 {
+		x = append(x, i)
+		i--
+	}
+}
+
+func whileNested(a [][]int) {
+	var x []int // want "Consider preallocating x with capacity 3 \\* len\\(a\\)$"
+	for range a {
+		j := 0
+		for j < 3 {
+			x = append(x, j)
+			j++
+		}
+	}
+}
+
+func whileIncrementTwice(a []byte) {
+	var x []byte
+	i := 0
+	for i < len(a) {
+		x = append(x, a[i])
+		i++
+		if a[i-1] == '\\' {
+			i++
+		}
+	}
+}
+
+func whileConditionalIncrement(n int) {
+	var x []int
+	i := 0
+	for i < n {
+		x = append(x, i)
+		if i%2 == 0 {
+			i++
+		}
+	}
+}
+
+func whileContinueBeforeIncrement(a []int) {
+	var x []int
+	i := 0
+	for i < len(a) {
+		if a[i] < 0 {
+			continue
+		}
+		x = append(x, a[i])
+		i++
+	}
+}
+
+func whileContinueInInnerLoop(a [][]int) {
+	var x []int // want "Consider preallocating x with capacity len\\(a\\)$"
+	i := 0
+	for i < len(a) {
+		for _, v := range a[i] {
+			if v < 0 {
+				continue
+			}
+		}
+		x = append(x, len(a[i]))
+		i++
+	}
+}
+
+func whileNotJustBefore(n int) {
+	var x []int
+	i := 0
+	n--
+	for i < n {
+		x = append(x, i)
+		i++
+	}
+}
+
+func whileNoCounter(a []int) {
+	var x []int
+	for len(a) > 0 {
+		x = append(x, a[0])
+		a = a[1:]
+	}
+}